@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+// Seconds are optional, e.g. 2025-02-01T00:00Z
+var timeFlagLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+var backfillFlags struct {
+	topic string
+	from  string
+	to    string
+}
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Reprocess the kafka messages of a time range",
+	Long: `Consume the messages received in [from, to) with a dedicated consumer group, through the processing pipeline.
+The consumer group of the process command is left untouched. The command exits once every partition has reached the end of the range.
+
+The valkey state shared with the process command is written conditionally, an older record never replaces a newer state:
+  - host states: ordered by updated_at, a cluster expires relative to its last host update
+  - cluster summaries & previous cluster states (changes): ordered by updated_at
+  - the cluster state debounce is disabled: every cluster state is written to the outputs`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.Logger()
+
+		ctx := common.SetupSignalHandler(context.Background())
+
+		from, err := parseTimeFlag(backfillFlags.from)
+		if err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+
+		to, err := parseTimeFlag(backfillFlags.to)
+		if err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+
+		if !from.Before(to) {
+			return fmt.Errorf("--from (%s) must be before --to (%s)", from, to)
+		}
+
+		topics := strings.Split(backfillFlags.topic, ",")
+
+		// Create Kafka client, closed with the cluster admin
+		client, err := factory.CreateKafkaClient(conf.Kafka)
+		if err != nil {
+			return fmt.Errorf("failed to create kafka client: %w", err)
+		}
+
+		admin, err := sarama.NewClusterAdminFromClient(client)
+		if err != nil {
+			client.Close()
+
+			return fmt.Errorf("failed to create kafka cluster admin: %w", err)
+		}
+
+		defer admin.Close()
+
+		ranges, err := computeOffsetRanges(client, topics, from, to)
+		if err != nil {
+			return err
+		}
+
+		// Create the ephemeral consumer group
+		groupID := fmt.Sprintf("%s-backfill-%d", conf.Kafka.Consumer.Group, time.Now().Unix())
+
+		consumerGroup, err := sarama.NewConsumerGroupFromClient(groupID, client)
+		if err != nil {
+			return fmt.Errorf("failed to create kafka consumer group: %w", err)
+		}
+
+		defer deleteConsumerGroup(admin, groupID)
+		defer consumerGroup.Close()
+
+		// Create Valkey client
+		valkeyClient, err := factory.CreateValkeyClient(ctx, conf.Valkey)
+		if err != nil {
+			return fmt.Errorf("failed to create valkey client: %w", err)
+		}
+
+		defer valkeyClient.Close()
+
+		// Create processings, metrics are not exposed
+		processings, err := newProcessings(ctx, prometheus.NewRegistry(), valkeyClient, true)
+		if err != nil {
+			return fmt.Errorf("failed to create processings: %w", err)
+		}
+
+		// Backfill
+		logger.Info("Backfilling", "topics", topics, "from", from, "to", to, "group", groupID, "ranges", ranges)
+
+		err = pipeline.NewRunner(consumerGroup, topics, processings.processing, processings.errorProcessing).
+			WithLogger(logger).
+			WithUseNumber(conf.Processing.LosslessNumbers).
+			WithOffsetRanges(ranges).
+			Run(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to backfill: %w", err)
+		}
+
+		logger.Info("Backfill done")
+
+		return nil
+	},
+}
+
+// computeOffsetRanges seeks every partition by timestamp, a partition without message after a time ends at its newest offset
+func computeOffsetRanges(client sarama.Client, topics []string, from, to time.Time) (pipeline.OffsetRanges, error) {
+	ret := make(pipeline.OffsetRanges, len(topics))
+
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitions of %s: %w", topic, err)
+		}
+
+		ret[topic] = make(map[int32]pipeline.OffsetRange, len(partitions))
+
+		for _, partition := range partitions {
+			start, err := getOffset(client, topic, partition, from)
+			if err != nil {
+				return nil, err
+			}
+
+			end, err := getOffset(client, topic, partition, to)
+			if err != nil {
+				return nil, err
+			}
+
+			ret[topic][partition] = pipeline.OffsetRange{Start: start, End: end}
+		}
+	}
+
+	return ret, nil
+}
+
+func getOffset(client sarama.Client, topic string, partition int32, date time.Time) (int64, error) {
+	ret, err := client.GetOffset(topic, partition, date.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get offset of %s/%d at %s: %w", topic, partition, date, err)
+	}
+
+	if ret != sarama.OffsetNewest {
+		return ret, nil
+	}
+
+	ret, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, partition, err)
+	}
+
+	return ret, nil
+}
+
+func deleteConsumerGroup(admin sarama.ClusterAdmin, groupID string) {
+	err := admin.DeleteConsumerGroup(groupID)
+	if err != nil {
+		log.Logger().Error(err, "failed to delete backfill consumer group", "group", groupID)
+	}
+}
+
+func parseTimeFlag(value string) (time.Time, error) {
+	for _, layout := range timeFlagLayouts {
+		ret, err := time.Parse(layout, value)
+		if err == nil {
+			return ret, nil
+		}
+	}
+
+	return time.Time{}, errors.New("expecting a RFC3339 time, e.g. 2025-02-01T00:00Z")
+}
+
+func init() {
+	backfillCmd.Flags().StringVar(&backfillFlags.topic, "topic", "", "comma separated topics to backfill")
+	backfillCmd.Flags().StringVar(&backfillFlags.from, "from", "", "beginning of the time range (included)")
+	backfillCmd.Flags().StringVar(&backfillFlags.to, "to", "", "end of the time range (excluded)")
+
+	_ = backfillCmd.MarkFlagRequired("topic")
+	_ = backfillCmd.MarkFlagRequired("from")
+	_ = backfillCmd.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(backfillCmd)
+}
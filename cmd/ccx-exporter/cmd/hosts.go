@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/host"
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+// stdio is used when no file is given
+const stdio = "-"
+
+// hostRecord is one line of a dump file. UpdatedAt orders the restored states, nil when the payload has none.
+type hostRecord struct {
+	ClusterID string                 `json:"cluster_id"`
+	HostID    string                 `json:"host_id"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+var hostsFlags struct {
+	out string
+	in  string
+}
+
+// hostsCmd represents the hosts command
+var hostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Inspect, dump and restore host states stored in valkey",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig()
+	},
+}
+
+var hostsGetCmd = &cobra.Command{
+	Use:   "get <cluster-id>",
+	Short: "Print the host states of a cluster, one json per line",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := common.SetupSignalHandler(context.Background())
+
+		valkeyRepo, client, err := newHostRepo(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer client.Close()
+
+		dateParser, err := factory.CreateDateParser(conf.Processing.Dates)
+		if err != nil {
+			return err
+		}
+
+		states, err := valkeyRepo.GetHostStates(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get host states: %w", err)
+		}
+
+		sort.Slice(states, func(i, j int) bool {
+			return states[i].HostID < states[j].HostID
+		})
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+
+		for _, state := range states {
+			err := encoder.Encode(mapToHostRecord(state, dateParser))
+			if err != nil {
+				return fmt.Errorf("failed to encode host state: %w", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+var hostsDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Dump every host state as ndjson",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.Logger()
+
+		ctx := common.SetupSignalHandler(context.Background())
+
+		valkeyRepo, client, err := newHostRepo(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer client.Close()
+
+		output := cmd.OutOrStdout()
+
+		if hostsFlags.out != stdio {
+			f, err := os.Create(hostsFlags.out)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", hostsFlags.out, err)
+			}
+
+			defer f.Close()
+
+			output = f
+		}
+
+		dateParser, err := factory.CreateDateParser(conf.Processing.Dates)
+		if err != nil {
+			return err
+		}
+
+		clusters, hosts, err := dumpHostStates(ctx, valkeyRepo, dateParser, output)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Dump done", "clusters", clusters, "hosts", hosts)
+
+		return nil
+	},
+}
+
+var hostsRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore host states from a ndjson dump",
+	Long: `Restore host states from a ndjson dump. Host states are written conditionally on their updated_at:
+a more recent host state already stored is kept, and a cluster expires relative to its last host update.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.Logger()
+
+		ctx := common.SetupSignalHandler(context.Background())
+
+		valkeyRepo, client, err := newHostRepo(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer client.Close()
+
+		input := cmd.InOrStdin()
+
+		if hostsFlags.in != stdio {
+			f, err := os.Open(hostsFlags.in)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", hostsFlags.in, err)
+			}
+
+			defer f.Close()
+
+			input = f
+		}
+
+		dateParser, err := factory.CreateDateParser(conf.Processing.Dates)
+		if err != nil {
+			return err
+		}
+
+		// Conditional writes, expiring relative to the host updated_at
+		hosts, skipped, err := restoreHostStates(ctx, valkeyRepo.WithBackfill(true), dateParser, input)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Restore done", "hosts", hosts, "skipped", skipped)
+
+		return nil
+	},
+}
+
+// newHostRepo creates the valkey repo used by admin commands, caller is responsible for closing the client.
+func newHostRepo(ctx context.Context) (host.ValkeyRepo, valkey.Client, error) {
+	client, err := factory.CreateValkeyClient(ctx, conf.Valkey)
+	if err != nil {
+		return host.ValkeyRepo{}, nil, fmt.Errorf("failed to create valkey client: %w", err)
+	}
+
+	ret := host.NewValkeyRepo(client, conf.Valkey.TTL).
+		WithLimits(conf.Valkey.MaxHostsPerCluster, conf.Valkey.MaxValueSize).
+		WithUseNumber(true) // dumps & restores are exact, whatever the processing config
+
+	return ret, client, nil
+}
+
+// dumpHostStates writes the host states of every cluster as ndjson
+func dumpHostStates(ctx context.Context, valkeyRepo host.ValkeyRepo, dateParser processing.DateParser, output io.Writer) (clusters int, hosts int, err error) {
+	writer := bufio.NewWriter(output)
+	encoder := json.NewEncoder(writer)
+
+	err = valkeyRepo.ListClusterIDs(ctx, func(clusterID string) error {
+		states, err := valkeyRepo.GetHostStates(ctx, clusterID)
+		if err != nil {
+			return fmt.Errorf("failed to get host states for %s: %w", clusterID, err)
+		}
+
+		for _, state := range states {
+			err := encoder.Encode(mapToHostRecord(state, dateParser))
+			if err != nil {
+				return fmt.Errorf("failed to encode host state: %w", err)
+			}
+		}
+
+		clusters++
+		hosts += len(states)
+
+		return nil
+	})
+	if err != nil {
+		return clusters, hosts, fmt.Errorf("failed to dump host states: %w", err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return clusters, hosts, fmt.Errorf("failed to flush output: %w", err)
+	}
+
+	return clusters, hosts, nil
+}
+
+// restoreHostStates writes the host states of a ndjson dump, an outdated host state is skipped
+func restoreHostStates(ctx context.Context, valkeyRepo host.ValkeyRepo, dateParser processing.DateParser, input io.Reader) (hosts int, skipped int, err error) {
+	decoder := json.NewDecoder(bufio.NewReader(input))
+	decoder.UseNumber()
+
+	for record := 1; ; record++ {
+		r := hostRecord{}
+
+		err := decoder.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return hosts, skipped, fmt.Errorf("failed to decode record %d: %w", record, err)
+		}
+
+		if r.ClusterID == "" || r.HostID == "" {
+			return hosts, skipped, fmt.Errorf("invalid record %d: cluster_id and host_id are mandatory", record)
+		}
+
+		err = valkeyRepo.WriteHostState(ctx, mapToHostState(r, dateParser))
+		if errors.Is(err, repo.ErrOutdated) {
+			skipped++
+
+			continue
+		}
+
+		if err != nil {
+			return hosts, skipped, fmt.Errorf("failed to write host state %s/%s: %w", r.ClusterID, r.HostID, err)
+		}
+
+		hosts++
+	}
+
+	return hosts, skipped, nil
+}
+
+func mapToHostRecord(state entity.HostState, dateParser processing.DateParser) hostRecord {
+	ret := hostRecord{
+		ClusterID: state.ClusterID,
+		HostID:    state.HostID,
+		Metadata:  state.Metadata,
+		Payload:   state.Payload,
+	}
+
+	// Same as the processing: updated_at is optional
+	_, updatedAt, err := dateParser.Extract(state.Payload, "updated_at")
+	if err == nil {
+		ret.UpdatedAt = &updatedAt
+	}
+
+	return ret
+}
+
+// mapToHostState reads updated_at from the payload for the dumps without it
+func mapToHostState(record hostRecord, dateParser processing.DateParser) entity.HostState {
+	ret := entity.HostState{
+		ClusterID: record.ClusterID,
+		HostID:    record.HostID,
+		Metadata:  record.Metadata,
+		Payload:   record.Payload,
+	}
+
+	if record.UpdatedAt != nil {
+		ret.UpdatedAt = *record.UpdatedAt
+	} else if _, updatedAt, err := dateParser.Extract(record.Payload, "updated_at"); err == nil {
+		ret.UpdatedAt = updatedAt
+	}
+
+	return ret
+}
+
+func init() {
+	hostsDumpCmd.Flags().StringVar(&hostsFlags.out, "out", stdio, "output file, '-' for stdout")
+	hostsRestoreCmd.Flags().StringVar(&hostsFlags.in, "in", stdio, "input file, '-' for stdin")
+
+	hostsCmd.AddCommand(hostsGetCmd, hostsDumpCmd, hostsRestoreCmd)
+	rootCmd.AddCommand(hostsCmd)
+}
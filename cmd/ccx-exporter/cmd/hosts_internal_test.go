@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/host"
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+func TestMapToHostState(t *testing.T) {
+	t.Parallel()
+
+	updatedAt := time.Date(2025, 2, 3, 20, 40, 0, 0, time.UTC)
+	recorded := updatedAt.Add(time.Hour)
+
+	type testCase struct {
+		name      string
+		record    hostRecord
+		updatedAt time.Time
+	}
+
+	testCases := []testCase{
+		{
+			name:      "updated_at recorded",
+			record:    hostRecord{UpdatedAt: &recorded, Payload: map[string]interface{}{"updated_at": "2025-02-03T20:40:00.000Z"}},
+			updatedAt: recorded,
+		},
+		{
+			name:      "dump without updated_at",
+			record:    hostRecord{Payload: map[string]interface{}{"updated_at": "2025-02-03T20:40:00.000Z"}},
+			updatedAt: updatedAt,
+		},
+		{
+			name:   "host state without updated_at",
+			record: hostRecord{Payload: map[string]interface{}{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.updatedAt, mapToHostState(tc.record, processing.DateParser{}).UpdatedAt)
+		})
+	}
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "quay.io/sclorg/valkey-7-c10s:bf91acf0827dc5db216164aafe3d34beb245dcec",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections tcp"),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "failed to start valkey instance")
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err, "failed to get valkey endpoint")
+
+	client, err := factory.CreateValkeyClient(ctx, config.Valkey{URL: endpoint})
+	require.NoError(t, err, "failed to create valkey client")
+
+	defer client.Close()
+
+	valkeyRepo := host.NewValkeyRepo(client, time.Minute).WithUseNumber(true)
+	dateParser := processing.DateParser{}
+
+	updatedAt := time.Now().UTC().Truncate(time.Millisecond).Add(-10 * time.Second)
+	state := entity.HostState{
+		ClusterID: "cluster-id",
+		HostID:    "host-id",
+		Payload:   map[string]interface{}{"status": "installed", "updated_at": processing.FormatDate(updatedAt)},
+		UpdatedAt: updatedAt,
+	}
+
+	err = valkeyRepo.WriteHostState(ctx, state)
+	require.NoError(t, err, "failed to write host state")
+
+	// Dump
+	dump := bytes.Buffer{}
+
+	clusters, hosts, err := dumpHostStates(ctx, valkeyRepo, dateParser, &dump)
+	require.NoError(t, err, "failed to dump host states")
+	assert.Equal(t, 1, clusters)
+	assert.Equal(t, 1, hosts)
+
+	record := hostRecord{}
+	require.NoError(t, json.Unmarshal(dump.Bytes(), &record), "failed to decode dump")
+	require.NotNil(t, record.UpdatedAt, "updated_at should be dumped")
+	assert.Equal(t, updatedAt, *record.UpdatedAt)
+
+	// Restore in an empty instance
+	err = client.Do(ctx, client.B().Flushall().Build()).Error()
+	require.NoError(t, err, "failed to clean valkey")
+
+	hosts, skipped, err := restoreHostStates(ctx, valkeyRepo.WithBackfill(true), dateParser, bytes.NewReader(dump.Bytes()))
+	require.NoError(t, err, "failed to restore host states")
+	assert.Equal(t, 1, hosts)
+	assert.Equal(t, 0, skipped)
+
+	// An older state doesn't replace the restored one
+	older := entity.HostState{
+		ClusterID: "cluster-id",
+		HostID:    "host-id",
+		Payload:   map[string]interface{}{"status": "installing"},
+		UpdatedAt: updatedAt.Add(-time.Second),
+	}
+
+	err = valkeyRepo.WriteHostState(ctx, older)
+	require.ErrorIs(t, err, repo.ErrOutdated, "older host state should be skipped")
+
+	states, err := valkeyRepo.GetHostStates(ctx, "cluster-id")
+	require.NoError(t, err, "failed to get host states")
+	require.Len(t, states, 1)
+	assert.Equal(t, "installed", states[0].Payload["status"], "restored host state was overwritten")
+}
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+)
+
+// noOffset is the committed offset of a partition never committed
+const noOffset = -1
+
+// partitionOffset is the state of one partition of the consumer group
+type partitionOffset struct {
+	Topic     string
+	Partition int32
+	Oldest    int64
+	Newest    int64
+	Committed int64
+}
+
+// Lag counts the messages not consumed yet, new groups start at the oldest offset
+func (p partitionOffset) Lag() int64 {
+	if p.Committed == noOffset {
+		return p.Newest - p.Oldest
+	}
+
+	return p.Newest - p.Committed
+}
+
+var offsetsFlags struct {
+	toTimestamp string
+	toOffset    int64
+	toLatest    bool
+}
+
+// offsetsCmd represents the offsets command
+var offsetsCmd = &cobra.Command{
+	Use:   "offsets",
+	Short: "Inspect and move the committed offsets of the consumer group",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig()
+	},
+}
+
+var offsetsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the committed offset & the lag of every partition",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, admin, err := newKafkaAdmin()
+		if err != nil {
+			return err
+		}
+
+		defer admin.Close()
+
+		members, err := countGroupMembers(admin)
+		if err != nil {
+			return err
+		}
+
+		offsets, err := listPartitionOffsets(client, admin)
+		if err != nil {
+			return err
+		}
+
+		return printPartitionOffsets(cmd.OutOrStdout(), members, offsets)
+	},
+}
+
+var offsetsResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Move the committed offsets of the consumer group",
+	Long: `Move the committed offset of every partition to a timestamp, an offset or the latest offset.
+The consumer group must not have any active member: stop the processing first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.Logger()
+
+		target, err := parseResetTarget(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, admin, err := newKafkaAdmin()
+		if err != nil {
+			return err
+		}
+
+		defer admin.Close()
+
+		members, err := countGroupMembers(admin)
+		if err != nil {
+			return err
+		}
+
+		if members > 0 {
+			return fmt.Errorf("consumer group %s has %d active members, stop them first", conf.Kafka.Consumer.Group, members)
+		}
+
+		offsets, err := listPartitionOffsets(client, admin)
+		if err != nil {
+			return err
+		}
+
+		offsetManager, err := sarama.NewOffsetManagerFromClient(conf.Kafka.Consumer.Group, client)
+		if err != nil {
+			return fmt.Errorf("failed to create offset manager: %w", err)
+		}
+
+		defer offsetManager.Close()
+
+		for i, o := range offsets {
+			offset, err := target(client, o)
+			if err != nil {
+				return err
+			}
+
+			err = resetOffset(offsetManager, o.Topic, o.Partition, offset)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Offset reset", "topic", o.Topic, "partition", o.Partition, "from", o.Committed, "to", offset)
+
+			offsets[i].Committed = offset
+		}
+
+		offsetManager.Commit()
+
+		return printPartitionOffsets(cmd.OutOrStdout(), members, offsets)
+	},
+}
+
+// parseResetTarget returns the function computing the new offset of a partition, exactly one flag is expected
+func parseResetTarget(cmd *cobra.Command) (func(client sarama.Client, o partitionOffset) (int64, error), error) {
+	targets := make([]func(client sarama.Client, o partitionOffset) (int64, error), 0, 1)
+
+	if cmd.Flags().Changed("to-timestamp") {
+		date, err := parseTimeFlag(offsetsFlags.toTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --to-timestamp: %w", err)
+		}
+
+		targets = append(targets, func(client sarama.Client, o partitionOffset) (int64, error) {
+			return getOffset(client, o.Topic, o.Partition, date)
+		})
+	}
+
+	if cmd.Flags().Changed("to-offset") {
+		targets = append(targets, func(client sarama.Client, o partitionOffset) (int64, error) {
+			if offsetsFlags.toOffset < o.Oldest || offsetsFlags.toOffset > o.Newest {
+				return 0, fmt.Errorf("offset %d is out of range [%d, %d] for %s/%d", offsetsFlags.toOffset, o.Oldest, o.Newest, o.Topic, o.Partition)
+			}
+
+			return offsetsFlags.toOffset, nil
+		})
+	}
+
+	if offsetsFlags.toLatest {
+		targets = append(targets, func(client sarama.Client, o partitionOffset) (int64, error) {
+			return o.Newest, nil
+		})
+	}
+
+	if len(targets) != 1 {
+		return nil, errors.New("exactly one of --to-timestamp, --to-offset or --to-latest is expected")
+	}
+
+	return targets[0], nil
+}
+
+// newKafkaAdmin creates a cluster admin & its client, closing the admin closes the client
+func newKafkaAdmin() (sarama.Client, sarama.ClusterAdmin, error) {
+	client, err := factory.CreateKafkaClient(conf.Kafka)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+
+		return nil, nil, fmt.Errorf("failed to create kafka cluster admin: %w", err)
+	}
+
+	return client, admin, nil
+}
+
+func countGroupMembers(admin sarama.ClusterAdmin) (int, error) {
+	groups, err := admin.DescribeConsumerGroups([]string{conf.Kafka.Consumer.Group})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe consumer group: %w", err)
+	}
+
+	if len(groups) != 1 {
+		return 0, fmt.Errorf("unexpected number of consumer groups: %d", len(groups))
+	}
+
+	if !errors.Is(groups[0].Err, sarama.ErrNoError) {
+		return 0, fmt.Errorf("failed to describe consumer group: %w", groups[0].Err)
+	}
+
+	return len(groups[0].Members), nil
+}
+
+// listPartitionOffsets returns the offsets of the partitions of the configured topics, sorted by topic & partition
+func listPartitionOffsets(client sarama.Client, admin sarama.ClusterAdmin) ([]partitionOffset, error) {
+	ret := make([]partitionOffset, 0)
+	topicPartitions := make(map[string][]int32)
+
+	for _, topic := range strings.Split(conf.Kafka.Consumer.Topic, ",") {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitions of %s: %w", topic, err)
+		}
+
+		topicPartitions[topic] = partitions
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(conf.Kafka.Consumer.Group, topicPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumer group offsets: %w", err)
+	}
+
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			o := partitionOffset{
+				Topic:     topic,
+				Partition: partition,
+				Committed: noOffset,
+			}
+
+			block := committed.GetBlock(topic, partition)
+			if block != nil && errors.Is(block.Err, sarama.ErrNoError) {
+				o.Committed = block.Offset
+			}
+
+			o.Oldest, err = client.GetOffset(topic, partition, sarama.OffsetOldest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get oldest offset of %s/%d: %w", topic, partition, err)
+			}
+
+			o.Newest, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, partition, err)
+			}
+
+			ret = append(ret, o)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Topic != ret[j].Topic {
+			return ret[i].Topic < ret[j].Topic
+		}
+
+		return ret[i].Partition < ret[j].Partition
+	})
+
+	return ret, nil
+}
+
+// resetOffset moves the offset of a partition: marking only goes forward, resetting only goes backward
+func resetOffset(offsetManager sarama.OffsetManager, topic string, partition int32, offset int64) error {
+	pom, err := offsetManager.ManagePartition(topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to manage partition %s/%d: %w", topic, partition, err)
+	}
+
+	defer pom.AsyncClose()
+
+	current, _ := pom.NextOffset()
+	if offset < current {
+		pom.ResetOffset(offset, "")
+	} else {
+		pom.MarkOffset(offset, "")
+	}
+
+	return nil
+}
+
+func printPartitionOffsets(out io.Writer, members int, offsets []partitionOffset) error {
+	fmt.Fprintf(out, "Group %s, %d active members\n", conf.Kafka.Consumer.Group, members)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "TOPIC\tPARTITION\tOLDEST\tNEWEST\tCOMMITTED\tLAG")
+
+	var totalLag int64
+
+	for _, o := range offsets {
+		committed := "-"
+		if o.Committed != noOffset {
+			committed = fmt.Sprintf("%d", o.Committed)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\n", o.Topic, o.Partition, o.Oldest, o.Newest, committed, o.Lag())
+
+		totalLag += o.Lag()
+	}
+
+	fmt.Fprintf(w, "TOTAL\t\t\t\t\t%d\n", totalLag)
+
+	return w.Flush()
+}
+
+func init() {
+	offsetsResetCmd.Flags().StringVar(&offsetsFlags.toTimestamp, "to-timestamp", "", "first offset at or after this RFC3339 time, e.g. 2025-02-01T00:00Z")
+	offsetsResetCmd.Flags().Int64Var(&offsetsFlags.toOffset, "to-offset", 0, "same offset for every partition")
+	offsetsResetCmd.Flags().BoolVar(&offsetsFlags.toLatest, "to-latest", false, "newest offset, skipping every pending message")
+
+	offsetsCmd.AddCommand(offsetsShowCmd, offsetsResetCmd)
+	rootCmd.AddCommand(offsetsCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+)
+
+// parseResetFlags parses args with the flags of the reset command, restoring their defaults first
+func parseResetFlags(t *testing.T, args []string) {
+	t.Helper()
+
+	offsetsResetCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		f.Changed = false
+		require.NoError(t, f.Value.Set(f.DefValue), "failed to restore flag default")
+	})
+
+	require.NoError(t, offsetsResetCmd.Flags().Parse(args), "failed to parse flags")
+}
+
+func TestParseResetTarget(t *testing.T) {
+	partition := partitionOffset{Topic: "events", Partition: 1, Oldest: 10, Newest: 20, Committed: 15}
+
+	type testCase struct {
+		name      string
+		args      []string
+		expectErr bool
+		offset    int64
+		offsetErr bool
+	}
+
+	testCases := []testCase{
+		{name: "no flag", args: []string{}, expectErr: true},
+		{name: "several flags", args: []string{"--to-latest", "--to-offset=12"}, expectErr: true},
+		{name: "invalid timestamp", args: []string{"--to-timestamp=yesterday"}, expectErr: true},
+		{name: "latest", args: []string{"--to-latest"}, offset: 20},
+		{name: "offset", args: []string{"--to-offset=12"}, offset: 12},
+		{name: "oldest offset", args: []string{"--to-offset=10"}, offset: 10},
+		{name: "newest offset", args: []string{"--to-offset=20"}, offset: 20},
+		{name: "offset before oldest", args: []string{"--to-offset=9"}, offsetErr: true},
+		{name: "offset after newest", args: []string{"--to-offset=21"}, offsetErr: true},
+		{name: "offset 0 out of range", args: []string{"--to-offset=0"}, offsetErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parseResetFlags(t, tc.args)
+
+			target, err := parseResetTarget(offsetsResetCmd)
+			if tc.expectErr {
+				assert.Error(t, err, "flags should be rejected")
+
+				return
+			}
+
+			require.NoError(t, err, "failed to parse reset target")
+
+			offset, err := target(nil, partition)
+			if tc.offsetErr {
+				assert.Error(t, err, "offset should be out of range")
+
+				return
+			}
+
+			require.NoError(t, err, "failed to compute offset")
+			assert.Equal(t, tc.offset, offset)
+		})
+	}
+}
+
+func TestPartitionOffsetLag(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name   string
+		offset partitionOffset
+		lag    int64
+	}
+
+	testCases := []testCase{
+		{name: "committed", offset: partitionOffset{Oldest: 10, Newest: 20, Committed: 15}, lag: 5},
+		{name: "up to date", offset: partitionOffset{Oldest: 10, Newest: 20, Committed: 20}, lag: 0},
+		{name: "never committed", offset: partitionOffset{Oldest: 10, Newest: 20, Committed: noOffset}, lag: 10},
+		{name: "never committed, empty", offset: partitionOffset{Oldest: 20, Newest: 20, Committed: noOffset}, lag: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.lag, tc.offset.Lag())
+		})
+	}
+}
+
+func TestPrintPartitionOffsets(t *testing.T) {
+	conf = &config.Config{}
+	conf.Kafka.Consumer.Group = "ccx-exporter"
+
+	out := bytes.Buffer{}
+
+	err := printPartitionOffsets(&out, 2, []partitionOffset{
+		{Topic: "events", Partition: 0, Oldest: 10, Newest: 20, Committed: 15},
+		{Topic: "events", Partition: 1, Oldest: 0, Newest: 7, Committed: noOffset},
+	})
+	require.NoError(t, err, "failed to print offsets")
+
+	expected := "Group ccx-exporter, 2 active members\n" +
+		"TOPIC   PARTITION  OLDEST  NEWEST  COMMITTED  LAG\n" +
+		"events  0          10      20      15         5\n" +
+		"events  1          0       7       -          7\n" +
+		"TOTAL                                         12\n"
+
+	assert.Equal(t, expected, out.String())
+}
@@ -7,41 +7,40 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/prometheus/client_golang/prometheus"
 	promversion "github.com/prometheus/common/version"
 	"github.com/spf13/cobra"
+	"github.com/valkey-io/valkey-go"
 
+	"github.com/openshift-assisted/ccx-exporter/internal/admin"
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/host"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/processingerror"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/projectedevent"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/rawevent"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/state"
 	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/health"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
 	"github.com/openshift-assisted/ccx-exporter/internal/log"
 	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/internal/tracing"
 	"github.com/openshift-assisted/ccx-exporter/internal/version"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
-var conf *config.Config
-
 // processCmd represents the process command
 var processCmd = &cobra.Command{
 	Use:   "process",
 	Short: "Process kafka events and push it to s3",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		var err error
-
-		conf, err = config.Parse(cfgFile)
+		err := initConfig()
 		if err != nil {
-			return fmt.Errorf("failed to parse config %s: %w", cfgFile, err)
-		}
-
-		// Init logger
-		err = log.Init(conf.Logs)
-		if err != nil {
-			return fmt.Errorf("failed to init logger: %w", err)
+			return err
 		}
 
 		logger := log.Logger()
@@ -80,9 +79,63 @@ var processCmd = &cobra.Command{
 			return
 		}
 
+		// Export traces, pending spans are flushed on exit
+		shutdownTracing, err := tracing.Init(rootCtx, conf.Tracing)
+		if err != nil {
+			logger.Error(err, "failed to init tracing")
+
+			return
+		}
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(rootCtx, conf.GracefulDuration)
+			defer cancel()
+
+			err := shutdownTracing(ctx)
+			if err != nil {
+				logger.Error(err, "failed to flush traces")
+			}
+		}()
+
+		// Create probes, dependencies are checked once created
+		consumerHealth := pipeline.NewHealth(conf.Health.StuckTimeout, clockwork.NewRealClock())
+
+		liveness := health.NewHandler(conf.Health.CheckTimeout)
+		liveness.Register("runner", consumerHealth.Live)
+
+		readiness := health.NewHandler(conf.Health.CheckTimeout)
+		readiness.Register("kafka", consumerHealth.Member)
+
+		handlers := map[string]http.Handler{
+			"/healthz": liveness,
+			"/readyz":  readiness,
+		}
+
+		// Create admin API
+		control := pipeline.NewControl()
+
+		if conf.Admin.Enabled {
+			adminHandler, err := admin.NewHandler(conf.Admin.Token, control, admin.LogLevel{Get: log.Level, Set: log.SetLevel}, logger)
+			if err != nil {
+				logger.Error(err, "failed to create admin handler")
+
+				return
+			}
+
+			handlers[admin.Prefix] = adminHandler
+		}
+
 		// Create & start prometheus server
 		registry := prometheus.NewRegistry()
-		promserver := factory.CreatePrometheusServer(conf.Metrics, registry)
+
+		err = factory.RegisterBuildInfo(registry)
+		if err != nil {
+			logger.Error(err, "failed to register build info")
+
+			return
+		}
+
+		promserver := factory.CreatePrometheusServer(conf.Metrics, registry, handlers)
 
 		go func() {
 			err := promserver.ListenAndServe()
@@ -116,14 +169,6 @@ var processCmd = &cobra.Command{
 			}
 		}()
 
-		// Create S3 clients for DQL
-		dlqS3Client, err := factory.CreateS3Client(ctx, conf.DeadLetterQueue)
-		if err != nil {
-			logger.Error(err, "failed to create dlq s3 client")
-
-			return
-		}
-
 		// Create Valkey client
 		valkeyClient, err := factory.CreateValkeyClient(ctx, conf.Valkey)
 		if err != nil {
@@ -136,44 +181,73 @@ var processCmd = &cobra.Command{
 			valkeyClient.Close()
 		}()
 
-		// Create S3 repo for processing error
-		processingErrorWriter := processingerror.NewS3Writer(dlqS3Client, conf.DeadLetterQueue.Bucket, conf.DeadLetterQueue.KeyPrefix)
+		readiness.Register("valkey", health.ValkeyPing(valkeyClient))
 
-		// Create S3 repo for projected event
-		projectedEventWriter, err := newS3Writer(ctx)
+		// Create processings
+		processings, err := newProcessings(ctx, registry, tracing.NewValkeyClient(valkeyClient), false)
 		if err != nil {
-			logger.Error(err, "failed to create s3 repo")
+			logger.Error(err, "failed to create processings")
 
 			return
 		}
 
-		// Create valkey repo for event
-		valkeyRepo := host.NewValkeyRepo(valkeyClient, conf.Valkey.TTL)
+		for name, check := range processings.checks {
+			readiness.Register(name, check)
+		}
 
-		// Create Main Processing
-		mainProcessing := processing.NewMain(valkeyRepo, projectedEventWriter)
+		// Create Runner & Start processing
+		topics := strings.Split(conf.Kafka.Consumer.Topic, ",")
 
-		decoratedProcessing, err := factory.DecorateProcessing(mainProcessing, registry)
+		runner := pipeline.NewRunner(kc, topics, processings.processing, processings.errorProcessing).
+			WithLogger(logger).
+			WithUseNumber(conf.Processing.LosslessNumbers).
+			WithHealth(consumerHealth).
+			WithControl(control)
+
+		// Kafka client resolving the oldest offsets of the partitions never committed
+		offsetsClient, err := factory.CreateKafkaClient(conf.Kafka)
 		if err != nil {
-			logger.Error(err, "failed to create decorated processing")
+			logger.Error(err, "failed to create kafka client")
 
 			return
 		}
 
-		// Create Error Processing
-		errorProcessing := processing.NewMainError(processingErrorWriter)
+		defer func() {
+			err := offsetsClient.Close()
+			if err != nil {
+				logger.Error(err, "failed to close kafka client")
+			}
+		}()
 
-		decoratedErrorProcessing, err := factory.DecorateErrorProcessing(errorProcessing, registry)
+		runner, err = runner.WithLagMetrics(registry, pipeline.MetricsConfig{Namespace: "kafka"}, offsetsClient)
 		if err != nil {
-			logger.Error(err, "failed to create decorated error processing")
+			logger.Error(err, "failed to create lag metrics")
 
 			return
 		}
 
-		// Create Runner & Start processing
-		topics := strings.Split(conf.Kafka.Consumer.Topic, ",")
+		// Create raw event archive
+		if conf.RawArchive.Enabled {
+			archiveS3Client, err := factory.CreateS3Client(ctx, conf.RawArchive.S3)
+			if err != nil {
+				logger.Error(err, "failed to create raw archive s3 client")
+
+				return
+			}
+
+			readiness.Register("s3-raw-archive", health.S3HeadBucket(archiveS3Client, conf.RawArchive.S3.Bucket))
 
-		runner := pipeline.NewRunner(kc, topics, decoratedProcessing, decoratedErrorProcessing).WithLogger(logger)
+			rawEventWriter := rawevent.NewS3Writer(archiveS3Client, conf.RawArchive.S3.Bucket, conf.RawArchive.S3.KeyPrefix)
+
+			decoratedArchive, err := factory.DecorateArchive(processing.NewArchive(rawEventWriter), registry)
+			if err != nil {
+				logger.Error(err, "failed to create decorated archive")
+
+				return
+			}
+
+			runner = runner.WithArchiver(decoratedArchive)
+		}
 
 		logger.V(2).Info("Start Processing")
 
@@ -186,20 +260,169 @@ var processCmd = &cobra.Command{
 	},
 }
 
-func newS3Writer(ctx context.Context) (repo.ProjectionWriter, error) {
+// processings are the decorated processings of the events, shared by the commands consuming kafka
+type processings struct {
+	processing      pipeline.Processing[entity.Event]
+	errorProcessing pipeline.ErrorProcessing
+
+	// checks are the readiness checks of the s3 buckets
+	checks map[string]health.Check
+}
+
+// newProcessings creates the processings, backfill processes old records: see the backfill command for the state it writes.
+func newProcessings(ctx context.Context, registry *prometheus.Registry, valkeyClient valkey.Client, backfill bool) (processings, error) {
+	// Create S3 clients for DQL
+	dlqS3Client, err := factory.CreateS3Client(ctx, conf.DeadLetterQueue)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create dlq s3 client: %w", err)
+	}
+
+	// Create S3 repo for processing error
+	processingErrorWriter := processingerror.NewS3Writer(dlqS3Client, conf.DeadLetterQueue.Bucket, conf.DeadLetterQueue.KeyPrefix)
+
+	checks := map[string]health.Check{
+		"s3-dlq": health.S3HeadBucket(dlqS3Client, conf.DeadLetterQueue.Bucket),
+	}
+
+	// Create stage metrics, shared by the repos & the main processing
+	stages, err := pipeline.NewStageMetrics(registry, clockwork.NewRealClock(), pipeline.MetricsConfig{Namespace: "processing"})
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create stage metrics: %w", err)
+	}
+
+	// Create late data schedule
+	schedule, err := late.NewSchedule(conf.Processing.LateData.ClosingTimes)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create late data schedule: %w", err)
+	}
+
+	// Create S3 repo for projected event
+	s3Writer, err := newS3Writer(ctx, schedule, checks, registry, stages)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create s3 repo: %w", err)
+	}
+
+	projectedEventWriter, err := projectedevent.NewAllowlistWriter(s3Writer, projectedevent.Allowlist(conf.Output.Allowlist), registry, pipeline.MetricsConfig{Namespace: "projection"})
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create allowlist writer: %w", err)
+	}
+
+	// Create valkey repo for event
+	valkeyRepo, err := host.NewValkeyRepo(valkeyClient, conf.Valkey.TTL).
+		WithLimits(conf.Valkey.MaxHostsPerCluster, conf.Valkey.MaxValueSize).
+		WithCache(conf.Valkey.Cache.TTL).
+		WithUseNumber(conf.Processing.LosslessNumbers).
+		WithBackfill(backfill).
+		WithStageMetrics(stages).
+		WithMetrics(registry, pipeline.MetricsConfig{Namespace: "valkey"})
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create valkey repo: %w", err)
+	}
+
+	// Create anonymization engine
+	anonymizer, err := factory.CreateAnonymizer(conf.Anonymization)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create anonymizer: %w", err)
+	}
+
+	// Create PII scrubber
+	scrubber, err := factory.CreateScrubber(conf.Anonymization.Scrubbing, registry)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create scrubber: %w", err)
+	}
+
+	// Create date parser, metrics are only counted once per event by the main processing
+	dateParser, err := factory.CreateDateParser(conf.Processing.Dates)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create date parser: %w", err)
+	}
+
+	mainDateParser, err := dateParser.WithMetrics(registry, pipeline.MetricsConfig{Namespace: "processing"})
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create date parser metrics: %w", err)
+	}
+
+	// Create Main Processing
+	mainProcessing := processing.NewMain(valkeyRepo, projectedEventWriter).
+		WithAnonymizer(anonymizer).
+		WithScrubber(scrubber).
+		WithDateParser(mainDateParser).
+		WithStageMetrics(stages).
+		WithUseNumber(conf.Processing.LosslessNumbers)
+
+	stateStore := state.NewValkeyStore(valkeyClient).WithStageMetrics(stages)
+
+	// Backfilled cluster states are written directly: the debounce only throttles the live ones
+	if conf.Processing.ClusterStateDebounce > 0 && !backfill {
+		mainProcessing = mainProcessing.WithClusterStateDebounce(stateStore, conf.Processing.ClusterStateDebounce, clockwork.NewRealClock())
+	}
+
+	if conf.Processing.ClusterSummaries.Enabled {
+		mainProcessing = mainProcessing.WithClusterSummaries(stateStore, conf.Processing.ClusterSummaries.TTL)
+	}
+
+	if conf.Processing.ClusterChanges.Enabled {
+		// Only exported fields are compared, the previous cluster states are bounded like the host states
+		filter := projectedevent.FieldFilter(conf.Output.Allowlist.Clusters)
+		mainProcessing = mainProcessing.WithClusterChanges(stateStore, conf.Processing.ClusterChanges.TTL, filter, conf.Valkey.MaxValueSize)
+	}
+
+	switch conf.Processing.UnknownEvents {
+	case config.UnknownEventPolicyFail:
+	case config.UnknownEventPolicyIgnore:
+		mainProcessing = mainProcessing.WithFallback(processing.IgnoreEvent{})
+	default:
+		return processings{}, fmt.Errorf("invalid processing config: unknown policy %s", conf.Processing.UnknownEvents)
+	}
+
+	decoratedProcessing, err := factory.DecorateProcessing(mainProcessing, schedule, dateParser, registry)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create decorated processing: %w", err)
+	}
+
+	// Create Error Processing
+	errorProcessing := processing.NewMainError(processingErrorWriter)
+
+	decoratedErrorProcessing, err := factory.DecorateErrorProcessing(errorProcessing, registry)
+	if err != nil {
+		return processings{}, fmt.Errorf("failed to create decorated error processing: %w", err)
+	}
+
+	return processings{
+		processing:      decoratedProcessing,
+		errorProcessing: decoratedErrorProcessing,
+		checks:          checks,
+	}, nil
+}
+
+// newS3Writer writes the projections to every output, adding the readiness check of each bucket to checks
+func newS3Writer(ctx context.Context, schedule late.Schedule, checks map[string]health.Check, registry prometheus.Registerer, stages *pipeline.StageMetrics) (repo.ProjectionWriter, error) {
 	writers := make([]repo.ProjectionWriter, 0)
 
 	if len(conf.Output.S3) == 0 {
 		return nil, errors.New("at least one s3 output must be specified")
 	}
 
-	for _, c := range conf.Output.S3 {
+	metrics, err := projectedevent.NewS3Metrics(registry, pipeline.MetricsConfig{Namespace: "s3"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 metrics: %w", err)
+	}
+
+	for i, c := range conf.Output.S3 {
 		s3Client, err := factory.CreateS3Client(ctx, c)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create s3 client: %w", err)
 		}
 
-		writer := projectedevent.NewS3Writer(s3Client, c.Bucket, c.KeyPrefix)
+		checks[fmt.Sprintf("s3-output-%d", i)] = health.S3HeadBucket(s3Client, c.Bucket)
+
+		writer, err := projectedevent.NewS3Writer(s3Client, c.Bucket, c.KeyPrefix).
+			WithMetrics(metrics).
+			WithStageMetrics(stages).
+			WithLateRouting(projectedevent.LateRouting(conf.Processing.LateData.Routing), processing.LateStreams(), schedule, clockwork.NewRealClock())
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure late routing: %w", err)
+		}
 
 		writers = append(writers, writer)
 	}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/projectedevent"
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+var rehydrateFlags struct {
+	since       time.Duration
+	outputIndex int
+	dryRun      bool
+}
+
+// rehydrateCmd represents the rehydrate-hosts command
+var rehydrateCmd = &cobra.Command{
+	Use:   "rehydrate-hosts",
+	Short: "Rebuild valkey host states from the cluster projections stored in s3",
+	Long: `Scan the recent cluster projections of one s3 output and write back the embedded hosts in valkey.
+Hosts older than the valkey TTL are skipped, host states already stored in valkey are never replaced by older ones.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return initConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.Logger()
+
+		ctx := common.SetupSignalHandler(context.Background())
+
+		if rehydrateFlags.outputIndex < 0 || rehydrateFlags.outputIndex >= len(conf.Output.S3) {
+			return fmt.Errorf("invalid output index %d, %d outputs configured", rehydrateFlags.outputIndex, len(conf.Output.S3))
+		}
+
+		since := rehydrateFlags.since
+		if since <= 0 {
+			since = conf.Valkey.TTL
+		}
+
+		// Create S3 reader
+		s3Conf := conf.Output.S3[rehydrateFlags.outputIndex]
+
+		s3Client, err := factory.CreateS3Client(ctx, s3Conf)
+		if err != nil {
+			return fmt.Errorf("failed to create s3 client: %w", err)
+		}
+
+		reader := projectedevent.NewS3Reader(s3Client, s3Conf.Bucket, s3Conf.KeyPrefix)
+
+		// Create valkey repo
+		hostRepo, valkeyClient, err := newHostRepo(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer valkeyClient.Close()
+
+		// Conditional writes, expiring relative to the host updated_at
+		valkeyRepo := hostRepo.WithBackfill(true)
+
+		dateParser, err := factory.CreateDateParser(conf.Processing.Dates)
+		if err != nil {
+			return err
+		}
+
+		// Rehydrate
+		logger.Info("Rehydrating host states", "bucket", s3Conf.Bucket, "prefix", s3Conf.KeyPrefix, "since", since, "dryRun", rehydrateFlags.dryRun)
+
+		stats, err := processing.NewRehydrate(reader, valkeyRepo, conf.Valkey.TTL, clockwork.NewRealClock()).
+			WithDryRun(rehydrateFlags.dryRun).
+			WithDateParser(dateParser).
+			Run(ctx, since)
+
+		logger.Info("Rehydration done",
+			"projections", stats.Projections,
+			"invalid", stats.Invalid,
+			"candidates", stats.Candidates,
+			"written", stats.Written,
+			"skippedExpired", stats.SkippedExpired,
+			"skippedNewer", stats.SkippedNewer,
+		)
+
+		if err != nil {
+			return fmt.Errorf("failed to rehydrate host states: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rehydrateCmd.Flags().DurationVar(&rehydrateFlags.since, "since", 0, "how far back to scan cluster projections (default to valkey ttl)")
+	rehydrateCmd.Flags().IntVar(&rehydrateFlags.outputIndex, "output", 0, "index of the s3 output to read from")
+	rehydrateCmd.Flags().BoolVar(&rehydrateFlags.dryRun, "dry-run", false, "only report what would be written")
+
+	rootCmd.AddCommand(rehydrateCmd)
+}
@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+	conf    *config.Config
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -20,6 +27,24 @@ func Execute() {
 	}
 }
 
+// initConfig parses the config file and initializes the logger
+func initConfig() error {
+	var err error
+
+	conf, err = config.Parse(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", cfgFile, err)
+	}
+
+	// Init logger
+	err = log.Init(conf.Logs)
+	if err != nil {
+		return fmt.Errorf("failed to init logger: %w", err)
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (no default value)")
 }
@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+// Prefix is the path of the admin API on the metrics server
+const Prefix = "/admin/"
+
+// Control pauses, resumes & stops the consumption, see pipeline.Control
+type Control interface {
+	PauseAll()
+	ResumeAll()
+	Leave() error
+	Status() pipeline.SessionStatus
+}
+
+// LogLevel reads & changes the verbosity of the logger
+type LogLevel struct {
+	Get func() int
+	Set func(level int) error
+}
+
+type logLevelBody struct {
+	Level *int `json:"level"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// Handler serves the admin API, every request is authenticated with a bearer token:
+//
+//	GET  /admin/claims    claims & offsets of the current session
+//	POST /admin/pause     pauses the consumption of every claim
+//	POST /admin/resume    resumes the consumption
+//	POST /admin/leave     leaves the consumer group, the process stops once the consumption is done
+//	GET  /admin/loglevel  current verbosity
+//	PUT  /admin/loglevel  changes the verbosity, e.g. {"level": 3}
+type Handler struct {
+	token    string
+	control  Control
+	logLevel LogLevel
+	logger   logr.Logger
+
+	router *http.ServeMux
+}
+
+func NewHandler(token string, control Control, logLevel LogLevel, logger logr.Logger) (*Handler, error) {
+	if token == "" {
+		return nil, errors.New("admin token can't be empty")
+	}
+
+	ret := &Handler{
+		token:    token,
+		control:  control,
+		logLevel: logLevel,
+		logger:   logger,
+		router:   http.NewServeMux(),
+	}
+
+	ret.router.HandleFunc("GET "+Prefix+"claims", ret.claims)
+	ret.router.HandleFunc("POST "+Prefix+"pause", ret.pause)
+	ret.router.HandleFunc("POST "+Prefix+"resume", ret.resume)
+	ret.router.HandleFunc("POST "+Prefix+"leave", ret.leave)
+	ret.router.HandleFunc("GET "+Prefix+"loglevel", ret.getLogLevel)
+	ret.router.HandleFunc("PUT "+Prefix+"loglevel", ret.setLogLevel)
+
+	return ret, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, errorBody{Error: "unauthorized"})
+
+		return
+	}
+
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) authenticated(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) claims(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.control.Status())
+}
+
+func (h *Handler) pause(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Pausing consumption", "remoteAddr", r.RemoteAddr)
+
+	h.control.PauseAll()
+
+	writeJSON(w, http.StatusOK, h.control.Status())
+}
+
+func (h *Handler) resume(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Resuming consumption", "remoteAddr", r.RemoteAddr)
+
+	h.control.ResumeAll()
+
+	writeJSON(w, http.StatusOK, h.control.Status())
+}
+
+// leave returns before the consumer is closed: closing waits for the messages in flight
+func (h *Handler) leave(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Leaving consumer group", "remoteAddr", r.RemoteAddr)
+
+	go func() {
+		err := h.control.Leave()
+		if err != nil {
+			h.logger.Error(err, "failed to leave consumer group")
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, h.control.Status())
+}
+
+func (h *Handler) getLogLevel(w http.ResponseWriter, _ *http.Request) {
+	level := h.logLevel.Get()
+
+	writeJSON(w, http.StatusOK, logLevelBody{Level: &level})
+}
+
+func (h *Handler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	body := logLevelBody{}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: fmt.Sprintf("invalid body: %v", err)})
+
+		return
+	}
+
+	if body.Level == nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: "level is required"})
+
+		return
+	}
+
+	err = h.logLevel.Set(*body.Level)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: err.Error()})
+
+		return
+	}
+
+	h.logger.Info("Log level changed", "level", *body.Level, "remoteAddr", r.RemoteAddr)
+
+	h.getLogLevel(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,214 @@
+package admin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/admin"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+type fakeControl struct {
+	lock   sync.Mutex
+	paused bool
+	left   chan struct{}
+}
+
+func (c *fakeControl) PauseAll()  { c.setPaused(true) }
+func (c *fakeControl) ResumeAll() { c.setPaused(false) }
+
+func (c *fakeControl) Leave() error {
+	close(c.left)
+
+	return nil
+}
+
+func (c *fakeControl) Status() pipeline.SessionStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return pipeline.SessionStatus{
+		MemberID: "member-1",
+		Paused:   c.paused,
+		Claims:   []pipeline.ClaimStatus{{Topic: "events", Partition: 0, Offset: 12, HighWaterMark: 15}},
+	}
+}
+
+func (c *fakeControl) setPaused(paused bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.paused = paused
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name          string
+		method        string
+		path          string
+		token         string
+		body          string
+		expectedCode  int
+		expectedBody  string
+		expectedLevel int
+	}
+
+	cases := []testCase{
+		{
+			name:          "missing token",
+			method:        http.MethodGet,
+			path:          "/admin/claims",
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"unauthorized"}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "invalid token",
+			method:        http.MethodPost,
+			path:          "/admin/pause",
+			token:         "wrong",
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"unauthorized"}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "claims",
+			method:        http.MethodGet,
+			path:          "/admin/claims",
+			token:         "secret",
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"memberId":"member-1","generationId":0,"paused":false,"claims":[{"topic":"events","partition":0,"initialOffset":0,"offset":12,"highWaterMark":15}]}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "pause",
+			method:        http.MethodPost,
+			path:          "/admin/pause",
+			token:         "secret",
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"memberId":"member-1","generationId":0,"paused":true,"claims":[{"topic":"events","partition":0,"initialOffset":0,"offset":12,"highWaterMark":15}]}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "wrong method",
+			method:        http.MethodGet,
+			path:          "/admin/pause",
+			token:         "secret",
+			expectedCode:  http.StatusMethodNotAllowed,
+			expectedLevel: 2,
+		},
+		{
+			name:          "get log level",
+			method:        http.MethodGet,
+			path:          "/admin/loglevel",
+			token:         "secret",
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"level":2}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "set log level",
+			method:        http.MethodPut,
+			path:          "/admin/loglevel",
+			token:         "secret",
+			body:          `{"level":5}`,
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"level":5}`,
+			expectedLevel: 5,
+		},
+		{
+			name:          "set invalid log level",
+			method:        http.MethodPut,
+			path:          "/admin/loglevel",
+			token:         "secret",
+			body:          `{"level":-1}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedBody:  `{"error":"negative level"}`,
+			expectedLevel: 2,
+		},
+		{
+			name:          "set log level without level",
+			method:        http.MethodPut,
+			path:          "/admin/loglevel",
+			token:         "secret",
+			body:          `{}`,
+			expectedCode:  http.StatusBadRequest,
+			expectedBody:  `{"error":"level is required"}`,
+			expectedLevel: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			level := 2
+			logLevel := admin.LogLevel{
+				Get: func() int { return level },
+				Set: func(l int) error {
+					if l < 0 {
+						return errors.New("negative level")
+					}
+
+					level = l
+
+					return nil
+				},
+			}
+
+			handler, err := admin.NewHandler("secret", &fakeControl{}, logLevel, logr.Discard())
+			require.NoError(t, err, "failed to create handler")
+
+			request := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			assert.Equal(t, tc.expectedLevel, level)
+
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerLeave(t *testing.T) {
+	t.Parallel()
+
+	control := &fakeControl{left: make(chan struct{})}
+
+	handler, err := admin.NewHandler("secret", control, admin.LogLevel{}, logr.Discard())
+	require.NoError(t, err, "failed to create handler")
+
+	request := httptest.NewRequest(http.MethodPost, "/admin/leave", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+
+	<-control.left
+}
+
+func TestNewHandlerWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	_, err := admin.NewHandler("", &fakeControl{}, admin.LogLevel{}, logr.Discard())
+	require.Error(t, err)
+}
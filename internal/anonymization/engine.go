@@ -0,0 +1,313 @@
+package anonymization
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Action string
+
+const (
+	// ActionHash replaces the value by its hash, or moves the hash to To and drops the value
+	ActionHash Action = "hash"
+	// ActionDrop removes the field
+	ActionDrop Action = "drop"
+	// ActionRename moves the value to To
+	ActionRename Action = "rename"
+	// ActionTruncate keeps the first Length characters
+	ActionTruncate Action = "truncate"
+	// ActionMask replaces every character but the last Length ones by maskChar
+	ActionMask Action = "mask"
+
+	maskChar = "*"
+)
+
+var (
+	errInvalidRule      = errors.New("invalid rule")
+	errFieldInvalidType = errors.New("field type was not the expected one")
+)
+
+// Rule describes the anonymization of one field of one event type.
+type Rule struct {
+	Event  string
+	Path   string // dot separated, "[]" iterates over arrays, e.g. host_inventory.interfaces[].mac_address
+	Action Action
+	To     string // hash & rename: destination field, sibling of the source field
+	Length int    // truncate: max length, mask: number of trailing characters left visible
+}
+
+// Pseudonym is one identifier computed from a value. Suffix is appended to the destination field name,
+// the main pseudonym has an empty suffix.
+type Pseudonym struct {
+	Suffix string
+	Value  string
+}
+
+// Hasher pseudonymizes a value at the event time (zero when unknown). The first pseudonym returned is the main one.
+type Hasher interface {
+	Hash(at time.Time, value []byte) ([]Pseudonym, error)
+}
+
+// HashFunc is a Hasher returning a single pseudonym, whatever the event time
+type HashFunc func(value []byte) (string, error)
+
+func (f HashFunc) Hash(_ time.Time, value []byte) ([]Pseudonym, error) {
+	ret, err := f(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Pseudonym{{Value: ret}}, nil
+}
+
+type compiledRule struct {
+	Rule
+	path path
+}
+
+// Engine applies the rules registered for an event type, in the configured order.
+type Engine struct {
+	rules  map[string][]compiledRule
+	hasher Hasher
+}
+
+// DefaultRules are the historical rules: user_name is replaced by a user_id hash, free_addresses are dropped.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Event: "ClusterState", Path: "user_name", Action: ActionHash, To: "user_id"},
+		{Event: "HostState", Path: "user_name", Action: ActionHash, To: "user_id"},
+		{Event: "HostState", Path: "free_addresses", Action: ActionDrop}, // not pushed by scraper and quite big for not that much value
+		{Event: "InfraEnv", Path: "user_name", Action: ActionHash, To: "user_id"},
+	}
+}
+
+func NewEngine(rules []Rule, hasher Hasher) (Engine, error) {
+	ret := Engine{
+		rules:  make(map[string][]compiledRule),
+		hasher: hasher,
+	}
+
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return ret, fmt.Errorf("rule %d (%s %s): %w", i, rule.Event, rule.Path, err)
+		}
+
+		ret.rules[rule.Event] = append(ret.rules[rule.Event], compiled)
+	}
+
+	return ret, nil
+}
+
+// NewDefaultEngine creates an engine with the default rules, hashing with md5.
+func NewDefaultEngine() Engine {
+	ret, err := NewEngine(DefaultRules(), HashFunc(MD5))
+	if err != nil {
+		panic(fmt.Sprintf("invalid default anonymization rules: %v", err))
+	}
+
+	return ret
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	ret := compiledRule{Rule: rule}
+
+	if rule.Event == "" {
+		return ret, fmt.Errorf("%w: missing event", errInvalidRule)
+	}
+
+	p, err := parsePath(rule.Path)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.path = p
+
+	switch rule.Action {
+	case ActionDrop:
+	case ActionHash:
+	case ActionRename:
+		if rule.To == "" {
+			return ret, fmt.Errorf("%w: rename requires a destination", errInvalidRule)
+		}
+	case ActionTruncate, ActionMask:
+		if rule.Length < 0 {
+			return ret, fmt.Errorf("%w: negative length", errInvalidRule)
+		}
+	default:
+		return ret, fmt.Errorf("%w: unknown action %s", errInvalidRule, rule.Action)
+	}
+
+	if rule.To != "" && rule.Action != ActionHash && rule.Action != ActionRename {
+		return ret, fmt.Errorf("%w: destination only supported by hash and rename", errInvalidRule)
+	}
+
+	if rule.To != "" && p.last().each {
+		return ret, fmt.Errorf("%w: destination not supported on array elements", errInvalidRule)
+	}
+
+	return ret, nil
+}
+
+// Apply anonymizes payload in place. Nested containers are copied before being modified.
+// eventTime selects the pseudonymization keys, zero when the event has no time.
+func (e Engine) Apply(eventName string, eventTime time.Time, payload map[string]interface{}) error {
+	for _, rule := range e.rules[eventName] {
+		for _, parent := range rule.path.parents(payload) {
+			err := e.applyRule(rule, eventTime, parent)
+			if err != nil {
+				return fmt.Errorf("failed to %s %s: %w", rule.Action, rule.Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e Engine) applyRule(rule compiledRule, eventTime time.Time, parent map[string]interface{}) error {
+	key := rule.path.last().key
+
+	value, found := parent[key]
+
+	if rule.Action == ActionDrop {
+		delete(parent, key)
+
+		return nil
+	}
+
+	if !found {
+		return nil
+	}
+
+	if rule.Action == ActionRename {
+		delete(parent, key)
+		parent[rule.To] = value
+
+		return nil
+	}
+
+	if rule.Action == ActionHash && rule.To != "" {
+		return e.hashTo(rule, eventTime, parent, key, value)
+	}
+
+	// Value transformation, on every element for arrays
+	if !rule.path.last().each {
+		transformed, err := e.transform(rule, eventTime, value)
+		if err != nil {
+			return err
+		}
+
+		parent[key] = transformed
+
+		return nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%w: expected an array", errFieldInvalidType)
+	}
+
+	items = copySlice(items)
+
+	for i, item := range items {
+		transformed, err := e.transform(rule, eventTime, item)
+		if err != nil {
+			return err
+		}
+
+		items[i] = transformed
+	}
+
+	parent[key] = items
+
+	return nil
+}
+
+// hashTo drops the value and sets every pseudonym, empty values are dropped without setting the destination
+func (e Engine) hashTo(rule compiledRule, eventTime time.Time, parent map[string]interface{}, key string, value interface{}) error {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: expected a string", errFieldInvalidType)
+	}
+
+	delete(parent, key)
+
+	if str == "" {
+		return nil
+	}
+
+	pseudonyms, err := e.hasher.Hash(eventTime, []byte(str))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pseudonyms {
+		parent[rule.To+p.Suffix] = p.Value
+	}
+
+	return nil
+}
+
+func (e Engine) transform(rule compiledRule, eventTime time.Time, value interface{}) (string, error) {
+	str, ok := value.(string)
+	if !ok {
+		// Failing is safer than leaking a value which can't be anonymized
+		return "", fmt.Errorf("%w: expected a string", errFieldInvalidType)
+	}
+
+	if str == "" {
+		return "", nil
+	}
+
+	switch rule.Action {
+	case ActionHash:
+		pseudonyms, err := e.hasher.Hash(eventTime, []byte(str))
+		if err != nil {
+			return "", err
+		}
+
+		return pseudonyms[0].Value, nil
+	case ActionTruncate:
+		return truncate(str, rule.Length), nil
+	case ActionMask:
+		return mask(str, rule.Length), nil
+	default:
+		return "", fmt.Errorf("%w: unexpected action %s", errInvalidRule, rule.Action)
+	}
+}
+
+func truncate(str string, length int) string {
+	runes := []rune(str)
+	if len(runes) <= length {
+		return str
+	}
+
+	return string(runes[:length])
+}
+
+func mask(str string, visible int) string {
+	runes := []rune(str)
+	if len(runes) <= visible {
+		return str
+	}
+
+	hidden := len(runes) - visible
+
+	return strings.Repeat(maskChar, hidden) + string(runes[hidden:])
+}
+
+// MD5 is the historical, unsalted, hash function
+func MD5(value []byte) (string, error) {
+	hash := md5.New()
+
+	_, err := hash.Write(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash value: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,182 @@
+package anonymization_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
+)
+
+var fakeHash = anonymization.HashFunc(func(value []byte) (string, error) {
+	return "hash(" + string(value) + ")", nil
+})
+
+func TestDefaultRules(t *testing.T) {
+	t.Parallel()
+
+	engine := anonymization.NewDefaultEngine()
+
+	payload := map[string]interface{}{
+		"user_name":      "john",
+		"free_addresses": "[]",
+		"id":             "host-id",
+	}
+
+	err := engine.Apply("HostState", time.Time{}, payload)
+	require.NoError(t, err, "failed to apply default rules")
+
+	assert.Equal(t, map[string]interface{}{
+		"user_id": "527bd5b5d689e2c32ae974c6229ff785", // md5(john)
+		"id":      "host-id",
+	}, payload)
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name     string
+		rules    []anonymization.Rule
+		payload  map[string]interface{}
+		valid    bool
+		expected map[string]interface{}
+	}
+
+	cases := []testCase{
+		{
+			name:     "hash to another field",
+			rules:    []anonymization.Rule{{Event: "E", Path: "user_name", Action: anonymization.ActionHash, To: "user_id"}},
+			payload:  map[string]interface{}{"user_name": "john"},
+			valid:    true,
+			expected: map[string]interface{}{"user_id": "hash(john)"},
+		},
+		{
+			name:     "hash empty value",
+			rules:    []anonymization.Rule{{Event: "E", Path: "user_name", Action: anonymization.ActionHash, To: "user_id"}},
+			payload:  map[string]interface{}{"user_name": ""},
+			valid:    true,
+			expected: map[string]interface{}{},
+		},
+		{
+			name:    "hash invalid type",
+			rules:   []anonymization.Rule{{Event: "E", Path: "user_name", Action: anonymization.ActionHash}},
+			payload: map[string]interface{}{"user_name": 12},
+		},
+		{
+			name:     "rule of another event",
+			rules:    []anonymization.Rule{{Event: "Other", Path: "user_name", Action: anonymization.ActionDrop}},
+			payload:  map[string]interface{}{"user_name": "john"},
+			valid:    true,
+			expected: map[string]interface{}{"user_name": "john"},
+		},
+		{
+			name:     "rename",
+			rules:    []anonymization.Rule{{Event: "E", Path: "a", Action: anonymization.ActionRename, To: "b"}},
+			payload:  map[string]interface{}{"a": 1},
+			valid:    true,
+			expected: map[string]interface{}{"b": 1},
+		},
+		{
+			name:     "truncate",
+			rules:    []anonymization.Rule{{Event: "E", Path: "a", Action: anonymization.ActionTruncate, Length: 3}},
+			payload:  map[string]interface{}{"a": "abcdef"},
+			valid:    true,
+			expected: map[string]interface{}{"a": "abc"},
+		},
+		{
+			name:     "mask",
+			rules:    []anonymization.Rule{{Event: "E", Path: "a", Action: anonymization.ActionMask, Length: 2}},
+			payload:  map[string]interface{}{"a": "abcdef"},
+			valid:    true,
+			expected: map[string]interface{}{"a": "****ef"},
+		},
+		{
+			name:  "nested path in arrays",
+			rules: []anonymization.Rule{{Event: "E", Path: "inventory.interfaces[].mac", Action: anonymization.ActionMask, Length: 2}},
+			payload: map[string]interface{}{"inventory": map[string]interface{}{"interfaces": []interface{}{
+				map[string]interface{}{"mac": "02:00:00:2f:c0:43"},
+				map[string]interface{}{"name": "ens3"},
+			}}},
+			valid: true,
+			expected: map[string]interface{}{"inventory": map[string]interface{}{"interfaces": []interface{}{
+				map[string]interface{}{"mac": "***************43"},
+				map[string]interface{}{"name": "ens3"},
+			}}},
+		},
+		{
+			name:     "array of strings",
+			rules:    []anonymization.Rule{{Event: "E", Path: "ips[]", Action: anonymization.ActionHash}},
+			payload:  map[string]interface{}{"ips": []interface{}{"a", "b"}},
+			valid:    true,
+			expected: map[string]interface{}{"ips": []interface{}{"hash(a)", "hash(b)"}},
+		},
+		{
+			name:     "path not matching",
+			rules:    []anonymization.Rule{{Event: "E", Path: "a.b.c", Action: anonymization.ActionDrop}},
+			payload:  map[string]interface{}{"a": "not a map"},
+			valid:    true,
+			expected: map[string]interface{}{"a": "not a map"},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			engine, err := anonymization.NewEngine(c.rules, fakeHash)
+			require.NoError(t, err, "failed to create engine")
+
+			err = engine.Apply("E", time.Time{}, c.payload)
+			assert.Equal(t, c.valid, err == nil, err)
+
+			if c.valid {
+				assert.Equal(t, c.expected, c.payload)
+			}
+		})
+	}
+}
+
+func TestApplyDoesNotModifyNestedInputs(t *testing.T) {
+	t.Parallel()
+
+	engine, err := anonymization.NewEngine([]anonymization.Rule{
+		{Event: "E", Path: "inventory.hostname", Action: anonymization.ActionDrop},
+	}, fakeHash)
+	require.NoError(t, err, "failed to create engine")
+
+	inventory := map[string]interface{}{"hostname": "host", "cpu": 4}
+	original := map[string]interface{}{"inventory": inventory}
+
+	// Shallow copy, as done by the processing
+	payload := map[string]interface{}{"inventory": inventory}
+
+	err = engine.Apply("E", time.Time{}, payload)
+	require.NoError(t, err, "failed to apply rules")
+
+	assert.Equal(t, map[string]interface{}{"inventory": map[string]interface{}{"cpu": 4}}, payload)
+	assert.Equal(t, map[string]interface{}{"inventory": map[string]interface{}{"hostname": "host", "cpu": 4}}, original, "input has been modified")
+}
+
+func TestInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	rules := []anonymization.Rule{
+		{Path: "a", Action: anonymization.ActionDrop},
+		{Event: "E", Path: "", Action: anonymization.ActionDrop},
+		{Event: "E", Path: "a..b", Action: anonymization.ActionDrop},
+		{Event: "E", Path: "a", Action: "unknown"},
+		{Event: "E", Path: "a", Action: anonymization.ActionRename},
+		{Event: "E", Path: "a[]", Action: anonymization.ActionHash, To: "b"},
+		{Event: "E", Path: "a", Action: anonymization.ActionMask, To: "b"},
+	}
+
+	for _, rule := range rules {
+		_, err := anonymization.NewEngine([]anonymization.Rule{rule}, fakeHash)
+		assert.Error(t, err, "rule should be invalid: %+v", rule)
+	}
+}
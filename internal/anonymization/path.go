@@ -0,0 +1,124 @@
+package anonymization
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const eachSuffix = "[]"
+
+var errInvalidPath = errors.New("invalid path")
+
+// segment is one element of a dot separated path. "interfaces[]" iterates over the interfaces array.
+type segment struct {
+	key  string
+	each bool
+}
+
+type path []segment
+
+func parsePath(str string) (path, error) {
+	if str == "" {
+		return nil, fmt.Errorf("%w: empty path", errInvalidPath)
+	}
+
+	parts := strings.Split(str, ".")
+	ret := make(path, 0, len(parts))
+
+	for _, part := range parts {
+		seg := segment{key: part}
+
+		if strings.HasSuffix(part, eachSuffix) {
+			seg.key = strings.TrimSuffix(part, eachSuffix)
+			seg.each = true
+		}
+
+		if seg.key == "" {
+			return nil, fmt.Errorf("%w: empty element in %s", errInvalidPath, str)
+		}
+
+		ret = append(ret, seg)
+	}
+
+	return ret, nil
+}
+
+func (p path) last() segment {
+	return p[len(p)-1]
+}
+
+// parents returns the maps holding the last element of the path.
+//
+// Payloads are shallow copies of the consumed event: every container on the way is copied
+// so modifying the returned maps never modifies the original event.
+// Missing keys and unexpected types are ignored: the path doesn't match.
+func (p path) parents(root map[string]interface{}) []map[string]interface{} {
+	return collectParents(root, p[:len(p)-1])
+}
+
+func collectParents(m map[string]interface{}, segments path) []map[string]interface{} {
+	if len(segments) == 0 {
+		return []map[string]interface{}{m}
+	}
+
+	seg := segments[0]
+
+	value, found := m[seg.key]
+	if !found {
+		return nil
+	}
+
+	if !seg.each {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		child = copyMap(child)
+		m[seg.key] = child
+
+		return collectParents(child, segments[1:])
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	items = copySlice(items)
+	m[seg.key] = items
+
+	ret := make([]map[string]interface{}, 0, len(items))
+
+	for i, item := range items {
+		child, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		child = copyMap(child)
+		items[i] = child
+
+		ret = append(ret, collectParents(child, segments[1:])...)
+	}
+
+	return ret
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+func copySlice(s []interface{}) []interface{} {
+	ret := make([]interface{}, len(s))
+	copy(ret, s)
+
+	return ret
+}
@@ -0,0 +1,172 @@
+package anonymization
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+type Mode string
+
+const (
+	// ModeLegacy hashes with unsalted md5
+	ModeLegacy Mode = "legacy"
+	// ModeHMAC uses HMAC-SHA256 with the active key
+	ModeHMAC Mode = "hmac"
+	// ModeMigration emits the hmac pseudonyms and the legacy one
+	ModeMigration Mode = "migration"
+
+	suffixKeyID  = "_key_id"
+	suffixLegacy = "_legacy"
+)
+
+var (
+	errInvalidKey  = errors.New("invalid pseudonymization key")
+	errNoActiveKey = errors.New("no active pseudonymization key")
+
+	// Key IDs are used as field name suffixes
+	rxKeyID = regexp.MustCompile("^[a-z0-9_]+$")
+)
+
+// Key is a HMAC secret, valid from NotBefore until NotAfter (zero means no end).
+// Several keys are valid at the same time during a rotation: the most recent one is the active one.
+type Key struct {
+	ID        string
+	Secret    []byte
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+func (k Key) validAt(t time.Time) bool {
+	if t.Before(k.NotBefore) {
+		return false
+	}
+
+	return k.NotAfter.IsZero() || t.Before(k.NotAfter)
+}
+
+// Pseudonymizer is a Hasher. Once a value is hashed into <to>, in hmac and migration modes it emits:
+//   - <to>: HMAC with the active key
+//   - <to>_key_id: ID of the active key
+//   - <to>_<id>: HMAC with every other key in its rotation window
+//   - <to>_legacy: md5 (migration mode only)
+type Pseudonymizer struct {
+	mode  Mode
+	keys  []Key
+	clock clockwork.Clock
+}
+
+func NewPseudonymizer(mode Mode, keys []Key, clock clockwork.Clock) (Pseudonymizer, error) {
+	ret := Pseudonymizer{
+		mode:  mode,
+		clock: clock,
+	}
+
+	switch mode {
+	case ModeLegacy:
+		return ret, nil
+	case ModeHMAC, ModeMigration:
+	default:
+		return ret, fmt.Errorf("unknown pseudonymization mode %s", mode)
+	}
+
+	if len(keys) == 0 {
+		return ret, fmt.Errorf("%w: mode %s requires at least one key", errInvalidKey, mode)
+	}
+
+	ids := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		if !rxKeyID.MatchString(key.ID) {
+			return ret, fmt.Errorf("%w: id %q must match %s", errInvalidKey, key.ID, rxKeyID)
+		}
+
+		if "_"+key.ID == suffixKeyID || "_"+key.ID == suffixLegacy {
+			return ret, fmt.Errorf("%w: id %s is reserved", errInvalidKey, key.ID)
+		}
+
+		if _, found := ids[key.ID]; found {
+			return ret, fmt.Errorf("%w: duplicated id %s", errInvalidKey, key.ID)
+		}
+
+		ids[key.ID] = struct{}{}
+
+		if len(key.Secret) == 0 {
+			return ret, fmt.Errorf("%w: empty secret for %s", errInvalidKey, key.ID)
+		}
+
+		if !key.NotAfter.IsZero() && !key.NotAfter.After(key.NotBefore) {
+			return ret, fmt.Errorf("%w: empty validity window for %s", errInvalidKey, key.ID)
+		}
+	}
+
+	// Most recent first: the first valid key is the active one
+	ret.keys = make([]Key, len(keys))
+	copy(ret.keys, keys)
+
+	sort.SliceStable(ret.keys, func(i, j int) bool {
+		return ret.keys[i].NotBefore.After(ret.keys[j].NotBefore)
+	})
+
+	return ret, nil
+}
+
+// Hash uses the keys valid at the event time, so a reprocessed event gets the same pseudonyms.
+// The current time is used for events without time.
+func (p Pseudonymizer) Hash(at time.Time, value []byte) ([]Pseudonym, error) {
+	if p.mode == ModeLegacy {
+		return HashFunc(MD5).Hash(at, value)
+	}
+
+	if at.IsZero() {
+		at = p.clock.Now()
+	}
+
+	ret := make([]Pseudonym, 0, 3)
+
+	for _, key := range p.keys {
+		if !key.validAt(at) {
+			continue
+		}
+
+		hash := hmac.New(sha256.New, key.Secret)
+
+		_, err := hash.Write(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash value: %w", err)
+		}
+
+		pseudonym := hex.EncodeToString(hash.Sum(nil))
+
+		if len(ret) == 0 {
+			ret = append(ret, Pseudonym{Value: pseudonym}, Pseudonym{Suffix: suffixKeyID, Value: key.ID})
+
+			continue
+		}
+
+		ret = append(ret, Pseudonym{Suffix: "_" + key.ID, Value: pseudonym})
+	}
+
+	if len(ret) == 0 {
+		// Failing is safer than emitting a non pseudonymized value
+		return nil, errNoActiveKey
+	}
+
+	if p.mode == ModeMigration {
+		legacy, err := MD5(value)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, Pseudonym{Suffix: suffixLegacy, Value: legacy})
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,166 @@
+package anonymization_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
+)
+
+func hmacHex(secret, value string) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(value))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func TestPseudonymizerRotation(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	keys := []anonymization.Key{
+		{ID: "k1", Secret: []byte("s1"), NotBefore: start, NotAfter: start.Add(48 * time.Hour)},
+		{ID: "k2", Secret: []byte("s2"), NotBefore: start.Add(24 * time.Hour)},
+	}
+
+	// The wall clock is only used for events without time
+	clock := clockwork.NewFakeClockAt(start.Add(72 * time.Hour))
+
+	pseudonymizer, err := anonymization.NewPseudonymizer(anonymization.ModeHMAC, keys, clock)
+	require.NoError(t, err, "failed to create pseudonymizer")
+
+	engine, err := anonymization.NewEngine([]anonymization.Rule{
+		{Event: "HostState", Path: "user_name", Action: anonymization.ActionHash, To: "user_id"},
+	}, pseudonymizer)
+	require.NoError(t, err, "failed to create engine")
+
+	apply := func(eventTime time.Time) map[string]interface{} {
+		payload := map[string]interface{}{"user_name": "john"}
+
+		err := engine.Apply("HostState", eventTime, payload)
+		require.NoError(t, err, "failed to apply rules")
+
+		return payload
+	}
+
+	// Only k1 is valid
+	assert.Equal(t, map[string]interface{}{
+		"user_id":        hmacHex("s1", "john"),
+		"user_id_key_id": "k1",
+	}, apply(start.Add(time.Hour)))
+
+	// Rotation window: k2 is active, k1 is still emitted
+	assert.Equal(t, map[string]interface{}{
+		"user_id":        hmacHex("s2", "john"),
+		"user_id_key_id": "k2",
+		"user_id_k1":     hmacHex("s1", "john"),
+	}, apply(start.Add(25*time.Hour)))
+
+	// k1 expired
+	assert.Equal(t, map[string]interface{}{
+		"user_id":        hmacHex("s2", "john"),
+		"user_id_key_id": "k2",
+	}, apply(start.Add(49*time.Hour)))
+
+	// No event time: k1 expired at the wall clock time
+	assert.Equal(t, map[string]interface{}{
+		"user_id":        hmacHex("s2", "john"),
+		"user_id_key_id": "k2",
+	}, apply(time.Time{}))
+}
+
+func TestPseudonymizerModes(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := []anonymization.Key{{ID: "k1", Secret: []byte("s1"), NotBefore: start}}
+
+	type testCase struct {
+		name     string
+		mode     anonymization.Mode
+		now      time.Time
+		expected []anonymization.Pseudonym
+		hasError bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "legacy",
+			mode: anonymization.ModeLegacy,
+			now:  start,
+			expected: []anonymization.Pseudonym{
+				{Value: "527bd5b5d689e2c32ae974c6229ff785"},
+			},
+		},
+		{
+			name: "migration",
+			mode: anonymization.ModeMigration,
+			now:  start,
+			expected: []anonymization.Pseudonym{
+				{Value: hmacHex("s1", "john")},
+				{Suffix: "_key_id", Value: "k1"},
+				{Suffix: "_legacy", Value: "527bd5b5d689e2c32ae974c6229ff785"},
+			},
+		},
+		{
+			name:     "no active key",
+			mode:     anonymization.ModeHMAC,
+			now:      start.Add(-time.Hour),
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pseudonymizer, err := anonymization.NewPseudonymizer(tc.mode, keys, clockwork.NewFakeClockAt(tc.now))
+			require.NoError(t, err, "failed to create pseudonymizer")
+
+			ret, err := pseudonymizer.Hash(time.Time{}, []byte("john"))
+			if tc.hasError {
+				assert.Error(t, err, "hash should fail")
+
+				return
+			}
+
+			require.NoError(t, err, "failed to hash")
+			assert.Equal(t, tc.expected, ret)
+		})
+	}
+}
+
+func TestNewPseudonymizerInvalidKeys(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string][]anonymization.Key{
+		"no key":         nil,
+		"invalid id":     {{ID: "K-1", Secret: []byte("s")}},
+		"reserved id":    {{ID: "legacy", Secret: []byte("s")}},
+		"duplicated id":  {{ID: "k1", Secret: []byte("s")}, {ID: "k1", Secret: []byte("s")}},
+		"empty secret":   {{ID: "k1"}},
+		"invalid window": {{ID: "k1", Secret: []byte("s"), NotBefore: start, NotAfter: start}},
+	}
+
+	for name, keys := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := anonymization.NewPseudonymizer(anonymization.ModeHMAC, keys, clockwork.NewFakeClock())
+			assert.Error(t, err, "keys should be rejected")
+		})
+	}
+
+	_, err := anonymization.NewPseudonymizer("sha1", nil, clockwork.NewFakeClock())
+	assert.Error(t, err, "unknown mode should be rejected")
+}
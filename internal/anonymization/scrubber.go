@@ -0,0 +1,240 @@
+package anonymization
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+const (
+	DetectorEmail = "email"
+	DetectorIPv4  = "ipv4"
+	DetectorIPv6  = "ipv6"
+	DetectorMAC   = "mac"
+)
+
+var errInvalidDetector = errors.New("invalid detector")
+
+// Detector finds PII in free text. Matches are replaced by Replacement, "[redacted:<name>]" when empty.
+type Detector struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+
+	// validate filters the candidates found by Pattern, when the pattern alone is too permissive
+	validate func(match string) bool
+}
+
+func (d Detector) replacement() string {
+	if d.Replacement != "" {
+		return d.Replacement
+	}
+
+	return "[redacted:" + d.Name + "]"
+}
+
+// BuiltinDetectors are the detectors available without configuration. MAC addresses are detected before
+// IPv6 addresses as both are colon separated.
+func BuiltinDetectors() []Detector {
+	return []Detector{
+		{
+			Name:    DetectorEmail,
+			Pattern: regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`),
+		},
+		{
+			Name:    DetectorMAC,
+			Pattern: regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b`),
+		},
+		{
+			Name:     DetectorIPv6,
+			Pattern:  regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){1,7}(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9a-f]{1,4}|:)`),
+			validate: isIPv6,
+		},
+		{
+			Name:    DetectorIPv4,
+			Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+		},
+	}
+}
+
+// isIPv6 validates the candidate: the pattern also matches timestamps or other colon separated values
+func isIPv6(match string) bool {
+	return net.ParseIP(match) != nil
+}
+
+// ScrubRule scrubs one field of one event type. Maps and arrays are scrubbed recursively, non string values
+// are left untouched. Detectors lists the detector names, every detector is used when empty.
+type ScrubRule struct {
+	Event     string
+	Path      string
+	Detectors []string
+}
+
+type compiledScrubRule struct {
+	ScrubRule
+	path      path
+	detectors []Detector
+}
+
+// Scrubber redacts PII found in free text fields. The zero value scrubs nothing.
+type Scrubber struct {
+	rules   map[string][]compiledScrubRule
+	counter *prometheus.CounterVec
+}
+
+// NewScrubber creates a scrubber using the builtin detectors and the custom ones.
+// Custom detectors can't override a builtin one.
+func NewScrubber(rules []ScrubRule, custom []Detector) (Scrubber, error) {
+	ret := Scrubber{
+		rules: make(map[string][]compiledScrubRule),
+	}
+
+	detectors := BuiltinDetectors()
+	byName := make(map[string]Detector, len(detectors)+len(custom))
+
+	for _, d := range detectors {
+		byName[d.Name] = d
+	}
+
+	for _, d := range custom {
+		if d.Name == "" || d.Pattern == nil {
+			return ret, fmt.Errorf("%w: name and pattern are mandatory", errInvalidDetector)
+		}
+
+		if _, found := byName[d.Name]; found {
+			return ret, fmt.Errorf("%w: duplicated name %s", errInvalidDetector, d.Name)
+		}
+
+		byName[d.Name] = d
+		detectors = append(detectors, d)
+	}
+
+	for i, rule := range rules {
+		compiled, err := compileScrubRule(rule, detectors, byName)
+		if err != nil {
+			return ret, fmt.Errorf("scrub rule %d (%s %s): %w", i, rule.Event, rule.Path, err)
+		}
+
+		ret.rules[rule.Event] = append(ret.rules[rule.Event], compiled)
+	}
+
+	return ret, nil
+}
+
+func compileScrubRule(rule ScrubRule, all []Detector, byName map[string]Detector) (compiledScrubRule, error) {
+	ret := compiledScrubRule{ScrubRule: rule}
+
+	if rule.Event == "" {
+		return ret, fmt.Errorf("%w: missing event", errInvalidRule)
+	}
+
+	p, err := parsePath(rule.Path)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.path = p
+
+	if len(rule.Detectors) == 0 {
+		ret.detectors = all
+
+		return ret, nil
+	}
+
+	for _, name := range rule.Detectors {
+		d, found := byName[name]
+		if !found {
+			return ret, fmt.Errorf("%w: unknown detector %s", errInvalidRule, name)
+		}
+
+		ret.detectors = append(ret.detectors, d)
+	}
+
+	return ret, nil
+}
+
+// WithMetrics counts redactions by event, path & detector
+func (s Scrubber) WithMetrics(registry prometheus.Registerer, config pipeline.MetricsConfig) (Scrubber, error) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "pii_redactions_total",
+		Help:      "Number of redacted values by event name, path and detector.",
+	}, []string{"name", "path", "detector"})
+
+	err := registry.Register(counter)
+	if err != nil {
+		return s, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	s.counter = counter
+
+	return s, nil
+}
+
+// Apply scrubs payload in place. Nested containers are copied before being modified.
+func (s Scrubber) Apply(eventName string, payload map[string]interface{}) {
+	for _, rule := range s.rules[eventName] {
+		key := rule.path.last().key
+
+		for _, parent := range rule.path.parents(payload) {
+			value, found := parent[key]
+			if !found {
+				continue
+			}
+
+			parent[key] = s.scrubValue(eventName, rule, value)
+		}
+	}
+}
+
+func (s Scrubber) scrubValue(eventName string, rule compiledScrubRule, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return s.scrubString(eventName, rule, v)
+	case map[string]interface{}:
+		ret := copyMap(v)
+
+		for k, item := range ret {
+			ret[k] = s.scrubValue(eventName, rule, item)
+		}
+
+		return ret
+	case []interface{}:
+		ret := copySlice(v)
+
+		for i, item := range ret {
+			ret[i] = s.scrubValue(eventName, rule, item)
+		}
+
+		return ret
+	default:
+		return value
+	}
+}
+
+func (s Scrubber) scrubString(eventName string, rule compiledScrubRule, str string) string {
+	for _, d := range rule.detectors {
+		count := 0
+
+		str = d.Pattern.ReplaceAllStringFunc(str, func(match string) string {
+			if d.validate != nil && !d.validate(match) {
+				return match
+			}
+
+			count++
+
+			return d.replacement()
+		})
+
+		if count > 0 && s.counter != nil {
+			s.counter.WithLabelValues(eventName, rule.Path, d.Name).Add(float64(count))
+		}
+	}
+
+	return str
+}
@@ -0,0 +1,149 @@
+package anonymization_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func TestScrubberDetectors(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "email",
+			input:    "Cluster created by john.doe+test@example.com",
+			expected: "Cluster created by [redacted:email]",
+		},
+		{
+			name:     "ipv4",
+			input:    "Host 10.0.0.12/24 can't reach 192.168.1.1",
+			expected: "Host [redacted:ipv4]/24 can't reach [redacted:ipv4]",
+		},
+		{
+			name:     "ipv6",
+			input:    "Address fe80::1ff:fe23:4567:890a: duplicated, ::1 ignored",
+			expected: "Address [redacted:ipv6]: duplicated, [redacted:ipv6] ignored",
+		},
+		{
+			name:     "mac",
+			input:    "Interface 52:54:00:AB:cd:01 and 52-54-00-ab-cd-02 are up",
+			expected: "Interface [redacted:mac] and [redacted:mac] are up",
+		},
+		{
+			name:     "no pii",
+			input:    "Installation started at 10:30:00, version 4.15.2",
+			expected: "Installation started at 10:30:00, version 4.15.2",
+		},
+	}
+
+	scrubber, err := anonymization.NewScrubber([]anonymization.ScrubRule{{Event: "Event", Path: "message"}}, nil)
+	require.NoError(t, err, "failed to create scrubber")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := map[string]interface{}{"message": tc.input}
+
+			scrubber.Apply("Event", payload)
+
+			assert.Equal(t, tc.expected, payload["message"])
+		})
+	}
+}
+
+func TestScrubberRules(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+
+	scrubber, err := anonymization.NewScrubber(
+		[]anonymization.ScrubRule{
+			{Event: "HostState", Path: "host_inventory.hostname", Detectors: []string{"hostname"}},
+			{Event: "HostState", Path: "host_inventory.interfaces[]", Detectors: []string{anonymization.DetectorMAC, anonymization.DetectorIPv4}},
+		},
+		[]anonymization.Detector{
+			{Name: "hostname", Pattern: regexp.MustCompile(`.+`), Replacement: "[hostname]"},
+		},
+	)
+	require.NoError(t, err, "failed to create scrubber")
+
+	scrubber, err = scrubber.WithMetrics(registry, pipeline.MetricsConfig{Namespace: "test"})
+	require.NoError(t, err, "failed to create metrics")
+
+	interfaces := []interface{}{
+		map[string]interface{}{
+			"mac_address":    "52:54:00:ab:cd:01",
+			"ipv4_addresses": []interface{}{"10.0.0.12/24", "10.0.1.12/24"},
+			"mtu":            float64(1500),
+		},
+	}
+
+	payload := map[string]interface{}{
+		"host_inventory": map[string]interface{}{
+			"hostname":   "master-0.example.com",
+			"interfaces": interfaces,
+		},
+	}
+
+	scrubber.Apply("HostState", payload)
+
+	assert.Equal(t, map[string]interface{}{
+		"host_inventory": map[string]interface{}{
+			"hostname": "[hostname]",
+			"interfaces": []interface{}{
+				map[string]interface{}{
+					"mac_address":    "[redacted:mac]",
+					"ipv4_addresses": []interface{}{"[redacted:ipv4]/24", "[redacted:ipv4]/24"},
+					"mtu":            float64(1500),
+				},
+			},
+		},
+	}, payload)
+
+	// Original containers are not modified
+	assert.Equal(t, "52:54:00:ab:cd:01", interfaces[0].(map[string]interface{})["mac_address"])
+
+	// Other events are not scrubbed
+	other := map[string]interface{}{"host_inventory": map[string]interface{}{"hostname": "master-0"}}
+	scrubber.Apply("ClusterState", other)
+	assert.Equal(t, "master-0", other["host_inventory"].(map[string]interface{})["hostname"])
+
+	expected := `
+# HELP test_pii_redactions_total Number of redacted values by event name, path and detector.
+# TYPE test_pii_redactions_total counter
+test_pii_redactions_total{detector="hostname",name="HostState",path="host_inventory.hostname"} 1
+test_pii_redactions_total{detector="ipv4",name="HostState",path="host_inventory.interfaces[]"} 2
+test_pii_redactions_total{detector="mac",name="HostState",path="host_inventory.interfaces[]"} 1
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+	assert.NoError(t, err, "unexpected metrics")
+}
+
+func TestNewScrubberInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := anonymization.NewScrubber([]anonymization.ScrubRule{{Event: "Event", Path: "message", Detectors: []string{"phone"}}}, nil)
+	assert.Error(t, err, "unknown detector should be rejected")
+
+	_, err = anonymization.NewScrubber([]anonymization.ScrubRule{{Event: "Event", Path: ""}}, nil)
+	assert.Error(t, err, "empty path should be rejected")
+
+	_, err = anonymization.NewScrubber(nil, []anonymization.Detector{{Name: "email", Pattern: regexp.MustCompile(`@`)}})
+	assert.Error(t, err, "builtin detector can't be overridden")
+}
@@ -0,0 +1,19 @@
+package common
+
+import "github.com/klauspost/compress/zstd"
+
+var (
+	// zstd encoder & decoder are safe for concurrent use with EncodeAll & DecodeAll
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// Compress appends the zstd compressed data to dst
+func Compress(data []byte, dst []byte) []byte {
+	return zstdEncoder.EncodeAll(data, dst)
+}
+
+// Decompress decodes zstd compressed data
+func Decompress(data []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(data, nil)
+}
@@ -49,6 +49,29 @@ func Parse(confFile string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse dlq s3 config: %w", err)
 	}
 
+	if ret.RawArchive.Enabled {
+		err = loadS3Config(&ret.RawArchive.S3)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse raw archive s3 config: %w", err)
+		}
+	}
+
+	if ret.Admin.Enabled {
+		err = loadSecretRecursive(ret.Admin.SecretPath, reflect.ValueOf(&ret.Admin).Elem())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin token: %w", err)
+		}
+	}
+
+	for i := range ret.Anonymization.Pseudonymization.Keys {
+		key := &ret.Anonymization.Pseudonymization.Keys[i]
+
+		err := loadSecretRecursive(key.SecretPath, reflect.ValueOf(key).Elem())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pseudonymization key (%d): %w", i, err)
+		}
+	}
+
 	return &ret, nil
 }
 
@@ -57,7 +80,21 @@ func setDefault() {
 	viper.SetDefault("logs.encoder", EncoderTypeConsole)
 	viper.SetDefault("gracefulDuration", "8s")
 	viper.SetDefault("metrics.port", 7777)
+	viper.SetDefault("health.stuckTimeout", "5m")
+	viper.SetDefault("health.checkTimeout", "5s")
+	viper.SetDefault("tracing.endpoint", "http://localhost:4318")
+	viper.SetDefault("tracing.serviceName", "ccx-exporter")
+	viper.SetDefault("tracing.sampleRatio", 1)
+	viper.SetDefault("tracing.timeout", "10s")
 	viper.SetDefault("output.s3", []S3{})
+	viper.SetDefault("anonymization.useDefaultRules", true)
+	viper.SetDefault("anonymization.pseudonymization.mode", "legacy")
+	viper.SetDefault("processing.unknownEvents", UnknownEventPolicyFail)
+	viper.SetDefault("processing.clusterSummaries.ttl", "720h")
+	viper.SetDefault("processing.clusterChanges.ttl", "720h")
+	viper.SetDefault("processing.dates.formats", []string{"legacy", "rfc3339", "epoch_millis"})
+	viper.SetDefault("processing.lateData.closingTimes", []string{"0 14 * * *"})
+	viper.SetDefault("processing.lateData.routing", "none")
 }
 
 func loadS3Config(s3 *S3) error {
@@ -1,21 +1,126 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"time"
+)
 
 type Config struct {
 	GracefulDuration time.Duration
 	Metrics          Metrics
+	Health           Health
+	Admin            Admin
 	Logs             Logs
+	Tracing          Tracing
 	DeadLetterQueue  S3
+	RawArchive       RawArchive
 	Kafka            Kafka
 	Valkey           Valkey
 	Output           Output
+	Anonymization    Anonymization
+	Processing       Processing
+}
+
+type Processing struct {
+	UnknownEvents UnknownEventPolicy
+	// ClusterStateDebounce re-emits a cluster state when one of its hosts is updated within this duration, 0 disables it
+	ClusterStateDebounce time.Duration
+	ClusterSummaries     ClusterSummaries
+	ClusterChanges       ClusterChanges
+	// LosslessNumbers decodes numbers as json.Number, large integers are not rounded anymore.
+	// Enabling it changes the ids of the states holding such numbers.
+	LosslessNumbers bool
+	Dates           Dates
+	LateData        LateData
+}
+
+// LateData configures when a day is closed & what to do with the projections received afterwards
+type LateData struct {
+	// ClosingTimes are "<minute> <hour> * * *" expressions (UTC), the previous day is closed at the last one
+	ClosingTimes []string
+	// Routing of late events, clusters & infra envs: none, prefix (<prefix>late/<day>/<eventType>/) or metadata (late=true)
+	Routing string
+}
+
+// Dates lists the accepted input date formats, dates are always projected in UTC with the legacy format
+type Dates struct {
+	// Formats are tried in order: legacy, rfc3339, epoch_millis or a go time layout
+	Formats []string
+	// Strict only accepts the legacy format (2006-01-02T15:04:05.9Z), whatever Formats
+	Strict bool
+}
+
+// ClusterSummaries emits a summary of each installation to the .cluster_summaries stream
+type ClusterSummaries struct {
+	Enabled bool
+	TTL     time.Duration // summaries expire TTL after their last update
 }
 
+// ClusterChanges emits the diff between consecutive cluster states to the .cluster_changes stream
+type ClusterChanges struct {
+	Enabled bool
+	TTL     time.Duration // previous cluster states expire TTL after their last update
+}
+
+type UnknownEventPolicy string
+
+const (
+	// UnknownEventPolicyFail sends unknown events to the dead letter queue
+	UnknownEventPolicyFail UnknownEventPolicy = "fail"
+	// UnknownEventPolicyIgnore drops unknown events
+	UnknownEventPolicyIgnore UnknownEventPolicy = "ignore"
+)
+
 type Metrics struct {
 	Port int
 }
 
+// Health configures the /healthz & /readyz probes, served with the metrics
+type Health struct {
+	// StuckTimeout fails the liveness probe when a message is processed for longer
+	StuckTimeout time.Duration
+	// CheckTimeout bounds every dependency check of the readiness probe
+	CheckTimeout time.Duration
+}
+
+// Admin serves the runtime admin API with the metrics, requests are authenticated with the bearer token
+type Admin struct {
+	Enabled    bool
+	SecretPath string
+	Token      string `secret:"token"`
+}
+
+func (a Admin) String() string {
+	token := "no token"
+	if a.Token != "" {
+		token = "token set"
+	}
+
+	return fmt.Sprintf("{Enabled:%v SecretPath:%s Token:%s}", a.Enabled, a.SecretPath, token)
+}
+
+// Tracing exports the spans to an OTLP/HTTP collector
+type Tracing struct {
+	Enabled     bool
+	Endpoint    string            // collector base url, spans are sent to <endpoint>/v1/traces
+	Headers     map[string]string // added to every export request, e.g. authorization
+	ServiceName string
+	SampleRatio float64 // ratio of the traces started by the exporter, propagated traces follow their parent decision
+	Timeout     time.Duration
+}
+
+func (t Tracing) String() string {
+	headers := make([]string, 0, len(t.Headers))
+	for key := range t.Headers {
+		headers = append(headers, key)
+	}
+
+	sort.Strings(headers)
+
+	return fmt.Sprintf("{Enabled:%v Endpoint:%s Headers:%v ServiceName:%s SampleRatio:%v Timeout:%v}", t.Enabled, t.Endpoint, headers, t.ServiceName, t.SampleRatio, t.Timeout)
+}
+
 type Logs struct {
 	Level   int
 	Encoder EncoderType
@@ -29,7 +134,25 @@ const (
 )
 
 type Output struct {
-	S3 []S3
+	S3        []S3
+	Allowlist Allowlist
+}
+
+// Allowlist lists the exported fields by projection type (dot separated paths, e.g. hosts[].id).
+// Fields of a projection type without allowlist are all exported.
+type Allowlist struct {
+	Events    []string
+	Clusters  []string
+	InfraEnvs []string
+	Hosts     []string
+	Summaries []string
+	Changes   []string
+}
+
+// RawArchive stores every consumed record as received, partitioned by record date
+type RawArchive struct {
+	Enabled bool
+	S3      S3
 }
 
 type S3 struct {
@@ -97,19 +220,120 @@ type KafkaConsumer struct {
 }
 
 type Valkey struct {
-	URL   string
-	TTL   time.Duration
-	Creds ValkeyCreds
+	URL      string // comma separated list of addresses (seeds in cluster mode, sentinels in sentinel mode)
+	Mode     ValkeyMode
+	TTL      time.Duration
+	Creds    ValkeyCreds
+	TLS      ValkeyTLS
+	Sentinel ValkeySentinel
+
+	// Limits, 0 means unlimited
+	MaxHostsPerCluster int
+	MaxValueSize       int // in bytes, once encoded
+
+	Cache ValkeyCache
 }
 
+// ValkeyCache configures client side caching (server assisted), a TTL of 0 disables it.
+type ValkeyCache struct {
+	TTL          time.Duration
+	SizeEachConn int // in bytes, 0 means valkey-go default
+}
+
+type ValkeyMode string
+
+const (
+	// ValkeyModeAuto lets the client detect a standalone instance or a cluster
+	ValkeyModeAuto       ValkeyMode = ""
+	ValkeyModeStandalone ValkeyMode = "standalone"
+	ValkeyModeCluster    ValkeyMode = "cluster"
+	ValkeyModeSentinel   ValkeyMode = "sentinel"
+)
+
 type ValkeyCreds struct {
+	Username string // ACL user, empty means default user
 	Password string
 }
 
 func (c ValkeyCreds) String() string {
-	if c.Password != "" {
+	switch {
+	case c.Username != "" && c.Password != "":
+		return "user and password set"
+	case c.Password != "":
 		return "password set"
+	case c.Username != "":
+		return "only user is set"
+	default:
+		return "no password"
 	}
+}
+
+type ValkeyTLS struct {
+	Enabled            bool
+	CAFile             string // PEM encoded, empty means system pool
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+type ValkeySentinel struct {
+	MasterSet string
+	Creds     ValkeyCreds
+}
+
+type Anonymization struct {
+	// UseDefaultRules applies the historical rules (user_name hashed into user_id, free_addresses dropped) before the configured ones
+	UseDefaultRules  bool
+	Rules            []AnonymizationRule
+	Pseudonymization Pseudonymization
+	Scrubbing        Scrubbing
+}
+
+// Scrubbing redacts PII (emails, ip & mac addresses, custom patterns) found in free text fields
+type Scrubbing struct {
+	Rules    []ScrubRule
+	Patterns []ScrubPattern
+}
+
+type ScrubRule struct {
+	Event     string   // event name: Event, ClusterState, HostState, InfraEnv
+	Path      string   // same syntax as anonymization rules, maps and arrays are scrubbed recursively
+	Detectors []string // email, ipv4, ipv6, mac or a pattern name, all of them when empty
+}
+
+type ScrubPattern struct {
+	Name        string
+	Regex       string
+	Replacement string // defaults to [redacted:<name>]
+}
+
+// Pseudonymization configures the hash action
+type Pseudonymization struct {
+	Mode string // legacy (md5), hmac or migration (hmac + legacy)
+	Keys []PseudonymizationKey
+}
+
+type PseudonymizationKey struct {
+	SecretPath string
+
+	ID        string // used as a field suffix: [a-z0-9_]+
+	Secret    string `secret:"secret"`
+	NotBefore time.Time
+	NotAfter  time.Time // zero means no end
+}
+
+func (k PseudonymizationKey) String() string {
+	secret := "no secret"
+	if k.Secret != "" {
+		secret = "secret set"
+	}
+
+	return fmt.Sprintf("{ID:%s Secret:%s NotBefore:%v NotAfter:%v}", k.ID, secret, k.NotBefore, k.NotAfter)
+}
 
-	return "no password"
+type AnonymizationRule struct {
+	Event  string // event name: Event, ClusterState, HostState, InfraEnv
+	Path   string // dot separated, "[]" iterates over arrays, e.g. host_inventory.interfaces[].mac_address
+	Action string // hash, drop, rename, truncate, mask
+	To     string // hash & rename: destination field
+	Length int    // truncate: max length, mask: number of trailing characters left visible
 }
@@ -13,16 +13,25 @@ type HostState struct {
 	HostID    string
 	Payload   map[string]interface{}
 	Metadata  map[string]interface{}
+
+	// UpdatedAt orders the writes, zero when unknown: the write is not ordered. Not filled on reads.
+	UpdatedAt time.Time
 }
 
 type Projection struct {
 	ID        string
 	Timestamp time.Time
 	Payload   map[string]interface{}
+
+	// Stream is the output the projection is written to
+	Stream string
 }
 
 type (
 	ProjectedClusterEvent Projection
 	ProjectedClusterState Projection
 	ProjectedInfraEnv     Projection
+	ProjectedHostState    Projection
+	ClusterSummary        Projection
+	ClusterChange         Projection
 )
@@ -0,0 +1,68 @@
+package host
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+)
+
+// Values are stored as: <version byte><zstd(json(State))>
+// Legacy values (plain json) are still readable: they start with '{'.
+const encodingVersionZstdJSON byte = 0x01
+
+var (
+	errEmptyValue      = errors.New("empty value")
+	errUnknownEncoding = errors.New("unknown encoding")
+)
+
+func encodeState(state State) ([]byte, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	ret := make([]byte, 1, len(data)/2)
+	ret[0] = encodingVersionZstdJSON
+
+	return common.Compress(data, ret), nil
+}
+
+// decodeState returns the state and the size of its json representation
+func decodeState(value []byte, useNumber bool) (State, int, error) {
+	ret := State{}
+
+	if len(value) == 0 {
+		return ret, 0, errEmptyValue
+	}
+
+	data := value
+
+	switch value[0] {
+	case '{':
+		// Legacy encoding: plain json
+	case encodingVersionZstdJSON:
+		decompressed, err := common.Decompress(value[1:])
+		if err != nil {
+			return ret, 0, fmt.Errorf("failed to decompress state: %w", err)
+		}
+
+		data = decompressed
+	default:
+		return ret, 0, fmt.Errorf("%w: %x", errUnknownEncoding, value[0])
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		decoder.UseNumber()
+	}
+
+	err := decoder.Decode(&ret)
+	if err != nil {
+		return ret, 0, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return ret, len(data), nil
+}
@@ -0,0 +1,88 @@
+package host
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeState(t *testing.T) {
+	t.Parallel()
+
+	state := State{
+		Metadata: map[string]interface{}{"versions": "v1"},
+		Payload:  map[string]interface{}{"id": "host-id", "host_inventory": map[string]interface{}{"hostname": "a"}},
+	}
+
+	data, err := encodeState(state)
+	require.NoError(t, err, "failed to encode state")
+	assert.Equal(t, encodingVersionZstdJSON, data[0], "unexpected encoding version")
+
+	res, size, err := decodeState(data, false)
+	require.NoError(t, err, "failed to decode state")
+	assert.Equal(t, state, res, "different state")
+	assert.Greater(t, size, len("{}"), "unexpected decoded size")
+}
+
+func TestDecodeState(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name     string
+		value    []byte
+		valid    bool
+		expected State
+	}
+
+	cases := []testCase{
+		{
+			name:     "legacy json",
+			value:    []byte(`{"Metadata":null,"Payload":{"test":"a"}}`),
+			valid:    true,
+			expected: State{Payload: map[string]interface{}{"test": "a"}},
+		},
+		{
+			name: "empty value",
+		},
+		{
+			name:  "unknown encoding",
+			value: []byte{0xff, 0x00},
+		},
+		{
+			name:  "invalid compressed data",
+			value: []byte{encodingVersionZstdJSON, 0x00, 0x01},
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, _, err := decodeState(c.value, false)
+			assert.Equal(t, c.valid, err == nil, err)
+
+			if c.valid {
+				assert.Equal(t, c.expected, res)
+			}
+		})
+	}
+}
+
+func TestDecodeStateUseNumber(t *testing.T) {
+	t.Parallel()
+
+	data, err := encodeState(State{Payload: map[string]interface{}{"size_bytes": json.Number("18446744073709551615")}})
+	require.NoError(t, err, "failed to encode state")
+
+	res, _, err := decodeState(data, true)
+	require.NoError(t, err, "failed to decode state")
+	assert.Equal(t, json.Number("18446744073709551615"), res.Payload["size_bytes"], "number should be exact")
+
+	res, _, err = decodeState(data, false)
+	require.NoError(t, err, "failed to decode state")
+	assert.Equal(t, float64(18446744073709551615), res.Payload["size_bytes"], "number should be a float64")
+}
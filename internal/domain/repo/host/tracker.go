@@ -0,0 +1,62 @@
+package host
+
+import (
+	"sync"
+	"time"
+)
+
+// Number of tracked clusters above which expired entries are swept on write
+const sweepThreshold = 10000
+
+// writeTracker remembers the clusters written by this instance.
+//
+// Server assisted invalidation is asynchronous: right after a WriteHostState, the client side cache
+// may still hold the previous value. Reads for a recently written cluster bypass the cache until the
+// cached value is guaranteed to be expired.
+type writeTracker struct {
+	mu       sync.Mutex
+	deadline map[string]time.Time
+	ttl      time.Duration
+}
+
+func newWriteTracker(ttl time.Duration) *writeTracker {
+	return &writeTracker{
+		deadline: make(map[string]time.Time),
+		ttl:      ttl,
+	}
+}
+
+func (w *writeTracker) markWritten(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+
+	if len(w.deadline) > sweepThreshold {
+		for k, d := range w.deadline {
+			if now.After(d) {
+				delete(w.deadline, k)
+			}
+		}
+	}
+
+	w.deadline[key] = now.Add(w.ttl)
+}
+
+func (w *writeTracker) recentlyWritten(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	d, ok := w.deadline[key]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(d) {
+		delete(w.deadline, key)
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,24 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newWriteTracker(50 * time.Millisecond)
+
+	assert.False(t, tracker.recentlyWritten("cluster-id"), "unknown key should not be tracked")
+
+	tracker.markWritten("cluster-id")
+	assert.True(t, tracker.recentlyWritten("cluster-id"), "key should be tracked right after a write")
+	assert.False(t, tracker.recentlyWritten("another-id"), "other keys should not be tracked")
+
+	assert.Eventually(t, func() bool {
+		return !tracker.recentlyWritten("cluster-id")
+	}, time.Second, 10*time.Millisecond, "key should not be tracked once ttl is over")
+}
@@ -4,24 +4,110 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/valkey-io/valkey-go"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
 const (
 	categoryInternalError     = "valkey_internal_error"
 	categoryValkeyClientError = "valkey_client"
+	categoryTooManyHosts      = "valkey_too_many_hosts"
+	categoryValueTooLarge     = "valkey_value_too_large"
+
+	cacheResultHit    = "hit"
+	cacheResultMiss   = "miss"
+	cacheResultBypass = "bypass"
+
+	stageValkeyGet = "valkey_get"
+	stageValkeySet = "valkey_set"
+
+	scanCount = 1000
+
+	scriptOutdated = -2
+)
+
+// writeHostStateScript sets the host state & extends the expiration of the cluster, unless a new host exceeds
+// the limit or a more recent state is stored.
+// KEYS[1]: cluster id, KEYS[2]: updated_at of the cluster hosts (sorted set, same slot).
+// ARGV: host id, value, expiration (unix ms), max hosts per cluster (0 means no limit),
+// updated_at (unix ms, 0 when unknown), backfill ("true": a host stored without updated_at is kept).
+// Returns -1 once written, -2 when outdated, the number of hosts when rejected.
+var writeHostStateScript = valkey.NewLuaScript(`
+local updatedAt = tonumber(ARGV[5])
+local exists = redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1
+if exists then
+	local stored = redis.call('ZSCORE', KEYS[2], ARGV[1])
+	if ARGV[6] == 'true' and (not stored or updatedAt == 0) then
+		return -2
+	end
+	if stored and updatedAt > 0 and tonumber(stored) > updatedAt then
+		return -2
+	end
+end
+local max = tonumber(ARGV[4])
+if max > 0 and not exists then
+	local count = redis.call('HLEN', KEYS[1])
+	if count >= max then
+		return count
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+if updatedAt > 0 then
+	redis.call('ZADD', KEYS[2], updatedAt, ARGV[1])
+else
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+local expireAt = tonumber(ARGV[3])
+for _, key in ipairs(KEYS) do
+	if redis.call('PEXPIRETIME', key) < expireAt then
+		redis.call('PEXPIREAT', key, expireAt)
+	end
+end
+return -1
+`)
+
+var (
+	errTooManyHosts  = errors.New("too many hosts for cluster")
+	errValueTooLarge = errors.New("host state too large")
 )
 
 type ValkeyRepo struct {
 	client     valkey.Client
 	expiration time.Duration
+
+	// 0 means no limit
+	maxHostsPerCluster int
+	maxValueSize       int
+
+	// 0 means client side caching is disabled
+	cacheTTL     time.Duration
+	recentWrites *writeTracker
+
+	metrics *valkeyMetrics
+	stages  *pipeline.StageMetrics // nil when disabled
+
+	// useNumber decodes numbers as json.Number instead of float64
+	useNumber bool
+
+	// backfill writes old states, see WithBackfill
+	backfill bool
+}
+
+type valkeyMetrics struct {
+	storedBytes  prometheus.Histogram
+	decodedBytes prometheus.Histogram
+	hosts        prometheus.Histogram
+	cache        *prometheus.CounterVec
 }
 
 func NewValkeyRepo(client valkey.Client, expiration time.Duration) ValkeyRepo {
@@ -31,20 +117,150 @@ func NewValkeyRepo(client valkey.Client, expiration time.Duration) ValkeyRepo {
 	}
 }
 
-func (r ValkeyRepo) WriteHostState(ctx context.Context, event entity.HostState) error {
+// WithLimits bounds the number of hosts stored per cluster and the size of a single encoded host state.
+func (r ValkeyRepo) WithLimits(maxHostsPerCluster int, maxValueSize int) ValkeyRepo {
+	r.maxHostsPerCluster = maxHostsPerCluster
+	r.maxValueSize = maxValueSize
+
+	return r
+}
+
+// WithCache enables client side caching of GetHostStates, relying on server assisted invalidation.
+func (r ValkeyRepo) WithCache(ttl time.Duration) ValkeyRepo {
+	r.cacheTTL = ttl
+	r.recentWrites = nil
+
+	if ttl > 0 {
+		r.recentWrites = newWriteTracker(ttl)
+	}
+
+	return r
+}
+
+// WithUseNumber keeps numbers of the stored host states as json.Number.
+func (r ValkeyRepo) WithUseNumber(useNumber bool) ValkeyRepo {
+	r.useNumber = useNumber
+
+	return r
+}
+
+// WithBackfill is used to write old states (rehydration, backfill): the cluster expires relative to the host
+// updated_at instead of now, and a host stored without updated_at is never replaced.
+func (r ValkeyRepo) WithBackfill(backfill bool) ValkeyRepo {
+	r.backfill = backfill
+
+	return r
+}
+
+func (r ValkeyRepo) WithMetrics(registry prometheus.Registerer, config pipeline.MetricsConfig) (ValkeyRepo, error) {
+	buckets := config.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.ExponentialBuckets(1024, 4, 10) // 1KiB -> 256MiB
+	}
+
+	storedBytes := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "cluster_stored_bytes",
+		Help:      "Encoded bytes stored for a cluster, measured when host states are read.",
+		Buckets:   buckets,
+	})
+
+	decodedBytes := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "cluster_decoded_bytes",
+		Help:      "Decoded (json) size of the host states of a cluster, measured when host states are read.",
+		Buckets:   buckets,
+	})
+
+	hosts := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "cluster_hosts",
+		Help:      "Number of hosts stored for a cluster, measured when host states are read.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11), // 1 -> 1024
+	})
+
+	cache := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "host_states_cache_total",
+		Help:      "Host states lookups by client side cache result (hit, miss, bypass).",
+	}, []string{"result"})
+
+	for _, collector := range []prometheus.Collector{storedBytes, decodedBytes, hosts, cache} {
+		err := registry.Register(collector)
+		if err != nil {
+			return r, fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+
+	r.metrics = &valkeyMetrics{
+		storedBytes:  storedBytes,
+		decodedBytes: decodedBytes,
+		hosts:        hosts,
+		cache:        cache,
+	}
+
+	return r, nil
+}
+
+// WithStageMetrics measures the reads & writes as the valkey_get & valkey_set stages
+func (r ValkeyRepo) WithStageMetrics(stages *pipeline.StageMetrics) ValkeyRepo {
+	r.stages = stages
+
+	return r
+}
+
+func (r ValkeyRepo) WriteHostState(ctx context.Context, event entity.HostState) (err error) {
+	end := r.stages.Start(stageValkeySet)
+	defer func() { end(err) }()
+
 	// Convert to local model
 	state := mapToModels(event)
 
-	// Marshal local model
-	data, err := json.Marshal(state)
+	// Encode local model
+	data, err := encodeState(state)
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryInternalError, nil, "failed to marshal data")
+		return common.NewErrProcessingError(err, categoryInternalError, nil, "failed to encode data")
+	}
+
+	// Check limits
+	if r.maxValueSize > 0 && len(data) > r.maxValueSize {
+		return common.NewErrProcessingError(errValueTooLarge, categoryValueTooLarge, nil, "host state %s is %d bytes, limit is %d", event.HostID, len(data), r.maxValueSize)
+	}
+
+	// The cluster expires `expiration` after the last write, or after the host update when backfilling
+	expireAt := time.Now().Add(r.expiration)
+	if r.backfill && !event.UpdatedAt.IsZero() {
+		expireAt = event.UpdatedAt.Add(r.expiration)
+
+		if !expireAt.After(time.Now()) {
+			return repo.ErrOutdated
+		}
 	}
 
-	// Set property
-	command := r.client.B().Hset().Key(event.ClusterID).FieldValue().FieldValue(event.HostID, string(data)).Build()
+	updatedAt := int64(0)
+	if !event.UpdatedAt.IsZero() {
+		updatedAt = event.UpdatedAt.UnixMilli()
+	}
 
-	err = r.client.Do(ctx, command).Error()
+	// Invalidate local cache before writing: a concurrent read must not see the previous value once the script returns
+	if r.recentWrites != nil {
+		r.recentWrites.markWritten(event.ClusterID)
+	}
+
+	// Check the host limit & the ordering, set the property & the expiration atomically
+	resp := writeHostStateScript.Exec(ctx, r.client,
+		[]string{event.ClusterID, updatedAtKey(event.ClusterID)},
+		[]string{
+			event.HostID,
+			string(data),
+			strconv.FormatInt(expireAt.UnixMilli(), 10),
+			strconv.Itoa(r.maxHostsPerCluster),
+			strconv.FormatInt(updatedAt, 10),
+			strconv.FormatBool(r.backfill),
+		},
+	)
+
+	err = resp.Error()
 	if err != nil {
 		switch {
 		case r.isRetryable(err):
@@ -54,28 +270,33 @@ func (r ValkeyRepo) WriteHostState(ctx context.Context, event entity.HostState)
 		}
 	}
 
-	// Set expiration
-	expireCommand := r.client.B().Expire().Key(event.ClusterID).Seconds(int64(r.expiration.Seconds())).Build()
-
-	err = r.client.Do(ctx, expireCommand).Error()
+	count, err := resp.AsInt64()
 	if err != nil {
-		switch {
-		case r.isRetryable(err):
-			return common.NewRetryableErrProcessingError(err, categoryValkeyClientError, nil, "failed to set expiration")
-		default:
-			return common.NewErrProcessingError(err, categoryValkeyClientError, nil, "failed to set expiration")
-		}
+		return common.NewErrProcessingError(err, categoryInternalError, nil, "unexpected script response type for %s", event.ClusterID)
+	}
+
+	switch {
+	case count == scriptOutdated:
+		return repo.ErrOutdated
+	case count >= 0:
+		return common.NewErrProcessingError(errTooManyHosts, categoryTooManyHosts, nil, "cluster %s already has %d hosts, limit is %d", event.ClusterID, count, r.maxHostsPerCluster)
 	}
 
 	return nil
 }
 
-func (r ValkeyRepo) GetHostStates(ctx context.Context, clusterID string) ([]entity.HostState, error) {
-	command := r.client.B().Hgetall().Key(clusterID).Build()
+// updatedAtKey shares the slot of the cluster key (hash tag): both are updated by the same script
+func updatedAtKey(clusterID string) string {
+	return "host_updated_at:{" + clusterID + "}"
+}
+
+func (r ValkeyRepo) GetHostStates(ctx context.Context, clusterID string) (_ []entity.HostState, err error) {
+	end := r.stages.Start(stageValkeyGet)
+	defer func() { end(err) }()
 
-	resp := r.client.Do(ctx, command)
+	resp := r.hgetall(ctx, clusterID)
 
-	err := resp.Error()
+	err = resp.Error()
 	if err != nil {
 		switch {
 		case r.isRetryable(err):
@@ -91,11 +312,11 @@ func (r ValkeyRepo) GetHostStates(ctx context.Context, clusterID string) ([]enti
 	}
 
 	ret := make([]entity.HostState, 0, len(result))
+	storedBytes := 0
+	decodedBytes := 0
 
-	for hostID, jsonHost := range result {
-		model := State{}
-
-		err := json.Unmarshal([]byte(jsonHost), &model)
+	for hostID, value := range result {
+		model, size, err := decodeState([]byte(value), r.useNumber)
 		if err != nil {
 			input := pipeline.Input{Source: "valkey", Key: clusterID}
 
@@ -104,7 +325,7 @@ func (r ValkeyRepo) GetHostStates(ctx context.Context, clusterID string) ([]enti
 				input.Value = b
 			}
 
-			return nil, common.NewErrProcessingError(err, categoryInternalError, []pipeline.Input{input}, "failed to unmarshal hgetall response for %s %s", clusterID, hostID)
+			return nil, common.NewErrProcessingError(err, categoryInternalError, []pipeline.Input{input}, "failed to decode hgetall response for %s %s", clusterID, hostID)
 		}
 
 		hostState := mapToEntity(model)
@@ -113,11 +334,92 @@ func (r ValkeyRepo) GetHostStates(ctx context.Context, clusterID string) ([]enti
 		hostState.HostID = hostID
 
 		ret = append(ret, hostState)
+
+		storedBytes += len(value)
+		decodedBytes += size
+	}
+
+	if r.metrics != nil && len(result) > 0 {
+		r.metrics.storedBytes.Observe(float64(storedBytes))
+		r.metrics.decodedBytes.Observe(float64(decodedBytes))
+		r.metrics.hosts.Observe(float64(len(result)))
 	}
 
 	return ret, nil
 }
 
+// ListClusterIDs calls fn once per cluster having host states. Every node is scanned (cluster mode).
+func (r ValkeyRepo) ListClusterIDs(ctx context.Context, fn func(clusterID string) error) error {
+	seen := make(map[string]struct{})
+
+	for addr, node := range r.client.Nodes() {
+		cursor := uint64(0)
+
+		for {
+			command := node.B().Scan().Cursor(cursor).Count(scanCount).Type("hash").Build()
+
+			entry, err := node.Do(ctx, command).AsScanEntry()
+			if err != nil {
+				return fmt.Errorf("failed to scan %s: %w", addr, err)
+			}
+
+			for _, key := range entry.Elements {
+				// Replicas return the same keys as their primary
+				if _, found := seen[key]; found {
+					continue
+				}
+
+				seen[key] = struct{}{}
+
+				err := fn(key)
+				if err != nil {
+					return err
+				}
+			}
+
+			cursor = entry.Cursor
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r ValkeyRepo) hgetall(ctx context.Context, clusterID string) valkey.ValkeyResult {
+	if r.cacheTTL <= 0 {
+		return r.client.Do(ctx, r.client.B().Hgetall().Key(clusterID).Build())
+	}
+
+	if r.recentWrites.recentlyWritten(clusterID) {
+		r.countCache(cacheResultBypass)
+
+		return r.client.Do(ctx, r.client.B().Hgetall().Key(clusterID).Build())
+	}
+
+	resp := r.client.DoCache(ctx, r.client.B().Hgetall().Key(clusterID).Cache(), r.cacheTTL)
+	if resp.Error() != nil {
+		return resp
+	}
+
+	if resp.IsCacheHit() {
+		r.countCache(cacheResultHit)
+	} else {
+		r.countCache(cacheResultMiss)
+	}
+
+	return resp
+}
+
+func (r ValkeyRepo) countCache(result string) {
+	if r.metrics == nil {
+		return
+	}
+
+	r.metrics.cache.WithLabelValues(result).Inc()
+}
+
 func (r ValkeyRepo) isRetryable(err error) bool {
 	// Network error
 	if errors.Is(err, syscall.ECONNREFUSED) {
@@ -3,6 +3,7 @@ package host_test
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 
 	"github.com/openshift-assisted/ccx-exporter/internal/config"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/host"
 	"github.com/openshift-assisted/ccx-exporter/internal/factory"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
@@ -153,6 +155,101 @@ func (s *ValkeyDataLayerTestSuite) TestExpiration() {
 	assert.Greater(t, ttl, int64(45), "ttl is supposed to be 1min") // Keeping some margin
 }
 
+func (s *ValkeyDataLayerTestSuite) TestMaxHostsPerCluster() {
+	ctx := context.Background()
+	t := s.T()
+
+	repo := s.repo.WithLimits(2, 0)
+
+	for _, hostID := range []string{"host-1", "host-2"} {
+		err := repo.WriteHostState(ctx, entity.HostState{ClusterID: "cluster-id", HostID: hostID, Payload: map[string]interface{}{"test": "a"}})
+		require.NoError(t, err, "failed to write host state %s", hostID)
+	}
+
+	// Updating a known host is still allowed
+	err := repo.WriteHostState(ctx, entity.HostState{ClusterID: "cluster-id", HostID: "host-1", Payload: map[string]interface{}{"test": "b"}})
+	require.NoError(t, err, "failed to update host state")
+
+	err = repo.WriteHostState(ctx, entity.HostState{ClusterID: "cluster-id", HostID: "host-3", Payload: map[string]interface{}{"test": "a"}})
+	require.Error(t, err, "third host should be rejected")
+
+	pErr := pipeline.ErrProcessingError{}
+	require.ErrorAs(t, err, &pErr, "error should be a processing error")
+	assert.Equal(t, "valkey_too_many_hosts", pErr.Category, "unexpected category")
+
+	res, err := repo.GetHostStates(ctx, "cluster-id")
+	require.NoError(t, err, "failed to get host states")
+	assert.Len(t, res, 2, "unexpected number of host state: %d", len(res))
+}
+
+func (s *ValkeyDataLayerTestSuite) TestMaxValueSize() {
+	ctx := context.Background()
+	t := s.T()
+
+	repo := s.repo.WithLimits(0, 16)
+
+	hostState := entity.HostState{ClusterID: "cluster-id", HostID: "host-id", Payload: map[string]interface{}{"test": strings.Repeat("abcdefgh", 64)}}
+	err := repo.WriteHostState(ctx, hostState)
+	require.Error(t, err, "host state should be rejected")
+
+	pErr := pipeline.ErrProcessingError{}
+	require.ErrorAs(t, err, &pErr, "error should be a processing error")
+	assert.Equal(t, "valkey_value_too_large", pErr.Category, "unexpected category")
+}
+
+func (s *ValkeyDataLayerTestSuite) TestCacheIsInvalidatedOnWrite() {
+	ctx := context.Background()
+	t := s.T()
+
+	repo := s.repo.WithCache(time.Minute)
+
+	hostState := entity.HostState{ClusterID: "cluster-id", HostID: "host-id", Payload: map[string]interface{}{"test": "a"}}
+	err := repo.WriteHostState(ctx, hostState)
+	require.NoError(t, err, "failed to write host state (1)")
+
+	for i := 0; i < 2; i++ {
+		res, err := repo.GetHostStates(ctx, "cluster-id")
+		require.NoError(t, err, "failed to get host states")
+		require.Len(t, res, 1, "unexpected number of host state: %d", len(res))
+		assert.Equal(t, hostState, res[0], "different host state")
+	}
+
+	hostState.Payload = map[string]interface{}{"new": "data"}
+
+	err = repo.WriteHostState(ctx, hostState)
+	require.NoError(t, err, "failed to write host state (2)")
+
+	res, err := repo.GetHostStates(ctx, "cluster-id")
+	require.NoError(t, err, "failed to get host states")
+	require.Len(t, res, 1, "unexpected number of host state: %d", len(res))
+	assert.Equal(t, hostState, res[0], "stale host state")
+}
+
+func (s *ValkeyDataLayerTestSuite) TestListClusterIDs() {
+	ctx := context.Background()
+	t := s.T()
+
+	for _, clusterID := range []string{"cluster-1", "cluster-2"} {
+		err := s.repo.WriteHostState(ctx, entity.HostState{ClusterID: clusterID, HostID: "host-id", Payload: map[string]interface{}{"test": "a"}})
+		require.NoError(t, err, "failed to write host state for %s", clusterID)
+	}
+
+	// Not a host state
+	err := s.client.Do(ctx, s.client.B().Set().Key("other").Value("value").Build()).Error()
+	require.NoError(t, err, "failed to set string key")
+
+	res := make([]string, 0)
+
+	err = s.repo.ListClusterIDs(ctx, func(clusterID string) error {
+		res = append(res, clusterID)
+
+		return nil
+	})
+	require.NoError(t, err, "failed to list cluster ids")
+
+	assert.ElementsMatch(t, []string{"cluster-1", "cluster-2"}, res, "unexpected cluster ids")
+}
+
 func TestLosingConnection(t *testing.T) {
 	t.Parallel()
 
@@ -171,3 +268,50 @@ func TestLosingConnection(t *testing.T) {
 	require.Error(t, err, "get host states should fail")
 	require.ErrorIs(t, err, pipeline.ErrRetryableError, "error should be retryable: %v", reflect.TypeOf(err))
 }
+
+func (s *ValkeyDataLayerTestSuite) TestOutdatedHostState() {
+	ctx := context.Background()
+	t := s.T()
+
+	updatedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	hostState := entity.HostState{ClusterID: "cluster-id", HostID: "host-id", Payload: map[string]interface{}{"test": "b"}, UpdatedAt: updatedAt}
+	err := s.repo.WriteHostState(ctx, hostState)
+	require.NoError(t, err, "failed to write host state")
+
+	older := entity.HostState{ClusterID: "cluster-id", HostID: "host-id", Payload: map[string]interface{}{"test": "a"}, UpdatedAt: updatedAt.Add(-time.Second)}
+	err = s.repo.WriteHostState(ctx, older)
+	require.ErrorIs(t, err, repo.ErrOutdated, "older host state should be skipped")
+
+	res, err := s.repo.GetHostStates(ctx, "cluster-id")
+	require.NoError(t, err, "failed to get host states")
+	require.Len(t, res, 1, "unexpected number of host state: %d", len(res))
+	assert.Equal(t, hostState.Payload, res[0].Payload, "host state was overwritten")
+}
+
+func (s *ValkeyDataLayerTestSuite) TestBackfill() {
+	ctx := context.Background()
+	t := s.T()
+
+	backfill := s.repo.WithBackfill(true)
+
+	// Stored without updated_at: kept
+	err := s.repo.WriteHostState(ctx, entity.HostState{ClusterID: "cluster-id", HostID: "host-1", Payload: map[string]interface{}{"test": "a"}})
+	require.NoError(t, err, "failed to write host state")
+
+	err = backfill.WriteHostState(ctx, entity.HostState{ClusterID: "cluster-id", HostID: "host-1", Payload: map[string]interface{}{"test": "b"}, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, repo.ErrOutdated, "host stored without updated_at should be kept")
+
+	// Expired already
+	err = backfill.WriteHostState(ctx, entity.HostState{ClusterID: "other-id", HostID: "host-2", Payload: map[string]interface{}{"test": "a"}, UpdatedAt: time.Now().Add(-time.Hour)})
+	require.ErrorIs(t, err, repo.ErrOutdated, "expired host state should be skipped")
+
+	// Expires relative to updated_at
+	err = backfill.WriteHostState(ctx, entity.HostState{ClusterID: "other-id", HostID: "host-2", Payload: map[string]interface{}{"test": "a"}, UpdatedAt: time.Now().Add(-30 * time.Second)})
+	require.NoError(t, err, "failed to backfill host state")
+
+	ttl, err := s.client.Do(ctx, s.client.B().Ttl().Key("other-id").Build()).AsInt64()
+	require.NoError(t, err, "failed to get TTL")
+	assert.LessOrEqual(t, ttl, int64(30), "ttl should be relative to updated_at")
+	assert.Greater(t, ttl, int64(0), "ttl should be set")
+}
@@ -2,6 +2,10 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/IBM/sarama"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
@@ -9,6 +13,9 @@ import (
 
 //go:generate mockgen -source=interfaces.go -package=mock -destination=./mock/mock_repo.go
 
+// ErrOutdated is returned when a more recent state is already stored: the write is skipped
+var ErrOutdated = errors.New("a more recent state is stored")
+
 type ProcessingErrorWriter interface {
 	WriteProcessingError(ctx context.Context, pErr pipeline.ErrProcessingError) error
 }
@@ -17,6 +24,10 @@ type ProcessingError interface {
 	ProcessingErrorWriter
 }
 
+type RawEventWriter interface {
+	WriteRawEvent(ctx context.Context, msg *sarama.ConsumerMessage) error
+}
+
 type HostStateWriter interface {
 	WriteHostState(ctx context.Context, state entity.HostState) error
 }
@@ -25,11 +36,23 @@ type HostStateReader interface {
 	GetHostStates(ctx context.Context, clusterID string) ([]entity.HostState, error)
 }
 
+type HostStateLister interface {
+	ListClusterIDs(ctx context.Context, fn func(clusterID string) error) error
+}
+
 type HostState interface {
 	HostStateWriter
 	HostStateReader
 }
 
+// StateStore holds per cluster processing states, namespaced by kind
+type StateStore interface {
+	GetState(ctx context.Context, kind, clusterID string) ([]byte, bool, error)
+	SetState(ctx context.Context, kind, clusterID string, value []byte, ttl time.Duration) error
+	// CompareAndSetState sets the state only if it is still previous (nil when it wasn't stored), returns false otherwise
+	CompareAndSetState(ctx context.Context, kind, clusterID string, previous, value []byte, ttl time.Duration) (bool, error)
+}
+
 type ProjectedClusterEventWriter interface {
 	WriteProjectedClusterEvent(ctx context.Context, event entity.ProjectedClusterEvent) error
 }
@@ -38,12 +61,31 @@ type ProjectedClusterStateWriter interface {
 	WriteProjectedClusterState(ctx context.Context, state entity.ProjectedClusterState) error
 }
 
+type ProjectedClusterStateReader interface {
+	ReadProjectedClusterStates(ctx context.Context, from, to time.Time, fn func(entity.ProjectedClusterState) error) error
+}
+
 type ProjectedInfraEnvWriter interface {
 	WriteProjectedInfraEnv(ctx context.Context, infraEnv entity.ProjectedInfraEnv) error
 }
 
+type ProjectedHostStateWriter interface {
+	WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error
+}
+
+type ClusterSummaryWriter interface {
+	WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error
+}
+
+type ClusterChangeWriter interface {
+	WriteClusterChange(ctx context.Context, change entity.ClusterChange) error
+}
+
 type ProjectionWriter interface {
 	ProjectedClusterEventWriter
 	ProjectedClusterStateWriter
 	ProjectedInfraEnvWriter
+	ProjectedHostStateWriter
+	ClusterSummaryWriter
+	ClusterChangeWriter
 }
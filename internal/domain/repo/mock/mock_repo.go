@@ -12,7 +12,9 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	sarama "github.com/IBM/sarama"
 	gomock "go.uber.org/mock/gomock"
 
 	entity "github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
@@ -95,6 +97,44 @@ func (mr *MockProcessingErrorMockRecorder) WriteProcessingError(ctx, pErr any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProcessingError", reflect.TypeOf((*MockProcessingError)(nil).WriteProcessingError), ctx, pErr)
 }
 
+// MockRawEventWriter is a mock of RawEventWriter interface.
+type MockRawEventWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRawEventWriterMockRecorder
+	isgomock struct{}
+}
+
+// MockRawEventWriterMockRecorder is the mock recorder for MockRawEventWriter.
+type MockRawEventWriterMockRecorder struct {
+	mock *MockRawEventWriter
+}
+
+// NewMockRawEventWriter creates a new mock instance.
+func NewMockRawEventWriter(ctrl *gomock.Controller) *MockRawEventWriter {
+	mock := &MockRawEventWriter{ctrl: ctrl}
+	mock.recorder = &MockRawEventWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRawEventWriter) EXPECT() *MockRawEventWriterMockRecorder {
+	return m.recorder
+}
+
+// WriteRawEvent mocks base method.
+func (m *MockRawEventWriter) WriteRawEvent(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteRawEvent", ctx, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteRawEvent indicates an expected call of WriteRawEvent.
+func (mr *MockRawEventWriterMockRecorder) WriteRawEvent(ctx, msg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteRawEvent", reflect.TypeOf((*MockRawEventWriter)(nil).WriteRawEvent), ctx, msg)
+}
+
 // MockHostStateWriter is a mock of HostStateWriter interface.
 type MockHostStateWriter struct {
 	ctrl     *gomock.Controller
@@ -172,6 +212,44 @@ func (mr *MockHostStateReaderMockRecorder) GetHostStates(ctx, clusterID any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostStates", reflect.TypeOf((*MockHostStateReader)(nil).GetHostStates), ctx, clusterID)
 }
 
+// MockHostStateLister is a mock of HostStateLister interface.
+type MockHostStateLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockHostStateListerMockRecorder
+	isgomock struct{}
+}
+
+// MockHostStateListerMockRecorder is the mock recorder for MockHostStateLister.
+type MockHostStateListerMockRecorder struct {
+	mock *MockHostStateLister
+}
+
+// NewMockHostStateLister creates a new mock instance.
+func NewMockHostStateLister(ctrl *gomock.Controller) *MockHostStateLister {
+	mock := &MockHostStateLister{ctrl: ctrl}
+	mock.recorder = &MockHostStateListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHostStateLister) EXPECT() *MockHostStateListerMockRecorder {
+	return m.recorder
+}
+
+// ListClusterIDs mocks base method.
+func (m *MockHostStateLister) ListClusterIDs(ctx context.Context, fn func(string) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClusterIDs", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ListClusterIDs indicates an expected call of ListClusterIDs.
+func (mr *MockHostStateListerMockRecorder) ListClusterIDs(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClusterIDs", reflect.TypeOf((*MockHostStateLister)(nil).ListClusterIDs), ctx, fn)
+}
+
 // MockHostState is a mock of HostState interface.
 type MockHostState struct {
 	ctrl     *gomock.Controller
@@ -225,6 +303,75 @@ func (mr *MockHostStateMockRecorder) WriteHostState(ctx, state any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteHostState", reflect.TypeOf((*MockHostState)(nil).WriteHostState), ctx, state)
 }
 
+// MockStateStore is a mock of StateStore interface.
+type MockStateStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStateStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStateStoreMockRecorder is the mock recorder for MockStateStore.
+type MockStateStoreMockRecorder struct {
+	mock *MockStateStore
+}
+
+// NewMockStateStore creates a new mock instance.
+func NewMockStateStore(ctrl *gomock.Controller) *MockStateStore {
+	mock := &MockStateStore{ctrl: ctrl}
+	mock.recorder = &MockStateStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStateStore) EXPECT() *MockStateStoreMockRecorder {
+	return m.recorder
+}
+
+// CompareAndSetState mocks base method.
+func (m *MockStateStore) CompareAndSetState(ctx context.Context, kind, clusterID string, previous, value []byte, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndSetState", ctx, kind, clusterID, previous, value, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndSetState indicates an expected call of CompareAndSetState.
+func (mr *MockStateStoreMockRecorder) CompareAndSetState(ctx, kind, clusterID, previous, value, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndSetState", reflect.TypeOf((*MockStateStore)(nil).CompareAndSetState), ctx, kind, clusterID, previous, value, ttl)
+}
+
+// GetState mocks base method.
+func (m *MockStateStore) GetState(ctx context.Context, kind, clusterID string) ([]byte, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetState", ctx, kind, clusterID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetState indicates an expected call of GetState.
+func (mr *MockStateStoreMockRecorder) GetState(ctx, kind, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockStateStore)(nil).GetState), ctx, kind, clusterID)
+}
+
+// SetState mocks base method.
+func (m *MockStateStore) SetState(ctx context.Context, kind, clusterID string, value []byte, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetState", ctx, kind, clusterID, value, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetState indicates an expected call of SetState.
+func (mr *MockStateStoreMockRecorder) SetState(ctx, kind, clusterID, value, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetState", reflect.TypeOf((*MockStateStore)(nil).SetState), ctx, kind, clusterID, value, ttl)
+}
+
 // MockProjectedClusterEventWriter is a mock of ProjectedClusterEventWriter interface.
 type MockProjectedClusterEventWriter struct {
 	ctrl     *gomock.Controller
@@ -301,6 +448,44 @@ func (mr *MockProjectedClusterStateWriterMockRecorder) WriteProjectedClusterStat
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProjectedClusterState", reflect.TypeOf((*MockProjectedClusterStateWriter)(nil).WriteProjectedClusterState), ctx, state)
 }
 
+// MockProjectedClusterStateReader is a mock of ProjectedClusterStateReader interface.
+type MockProjectedClusterStateReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockProjectedClusterStateReaderMockRecorder
+	isgomock struct{}
+}
+
+// MockProjectedClusterStateReaderMockRecorder is the mock recorder for MockProjectedClusterStateReader.
+type MockProjectedClusterStateReaderMockRecorder struct {
+	mock *MockProjectedClusterStateReader
+}
+
+// NewMockProjectedClusterStateReader creates a new mock instance.
+func NewMockProjectedClusterStateReader(ctrl *gomock.Controller) *MockProjectedClusterStateReader {
+	mock := &MockProjectedClusterStateReader{ctrl: ctrl}
+	mock.recorder = &MockProjectedClusterStateReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProjectedClusterStateReader) EXPECT() *MockProjectedClusterStateReaderMockRecorder {
+	return m.recorder
+}
+
+// ReadProjectedClusterStates mocks base method.
+func (m *MockProjectedClusterStateReader) ReadProjectedClusterStates(ctx context.Context, from, to time.Time, fn func(entity.ProjectedClusterState) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadProjectedClusterStates", ctx, from, to, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReadProjectedClusterStates indicates an expected call of ReadProjectedClusterStates.
+func (mr *MockProjectedClusterStateReaderMockRecorder) ReadProjectedClusterStates(ctx, from, to, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadProjectedClusterStates", reflect.TypeOf((*MockProjectedClusterStateReader)(nil).ReadProjectedClusterStates), ctx, from, to, fn)
+}
+
 // MockProjectedInfraEnvWriter is a mock of ProjectedInfraEnvWriter interface.
 type MockProjectedInfraEnvWriter struct {
 	ctrl     *gomock.Controller
@@ -339,6 +524,120 @@ func (mr *MockProjectedInfraEnvWriterMockRecorder) WriteProjectedInfraEnv(ctx, i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProjectedInfraEnv", reflect.TypeOf((*MockProjectedInfraEnvWriter)(nil).WriteProjectedInfraEnv), ctx, infraEnv)
 }
 
+// MockProjectedHostStateWriter is a mock of ProjectedHostStateWriter interface.
+type MockProjectedHostStateWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockProjectedHostStateWriterMockRecorder
+	isgomock struct{}
+}
+
+// MockProjectedHostStateWriterMockRecorder is the mock recorder for MockProjectedHostStateWriter.
+type MockProjectedHostStateWriterMockRecorder struct {
+	mock *MockProjectedHostStateWriter
+}
+
+// NewMockProjectedHostStateWriter creates a new mock instance.
+func NewMockProjectedHostStateWriter(ctrl *gomock.Controller) *MockProjectedHostStateWriter {
+	mock := &MockProjectedHostStateWriter{ctrl: ctrl}
+	mock.recorder = &MockProjectedHostStateWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProjectedHostStateWriter) EXPECT() *MockProjectedHostStateWriterMockRecorder {
+	return m.recorder
+}
+
+// WriteProjectedHostState mocks base method.
+func (m *MockProjectedHostStateWriter) WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteProjectedHostState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteProjectedHostState indicates an expected call of WriteProjectedHostState.
+func (mr *MockProjectedHostStateWriterMockRecorder) WriteProjectedHostState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProjectedHostState", reflect.TypeOf((*MockProjectedHostStateWriter)(nil).WriteProjectedHostState), ctx, state)
+}
+
+// MockClusterSummaryWriter is a mock of ClusterSummaryWriter interface.
+type MockClusterSummaryWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockClusterSummaryWriterMockRecorder
+	isgomock struct{}
+}
+
+// MockClusterSummaryWriterMockRecorder is the mock recorder for MockClusterSummaryWriter.
+type MockClusterSummaryWriterMockRecorder struct {
+	mock *MockClusterSummaryWriter
+}
+
+// NewMockClusterSummaryWriter creates a new mock instance.
+func NewMockClusterSummaryWriter(ctrl *gomock.Controller) *MockClusterSummaryWriter {
+	mock := &MockClusterSummaryWriter{ctrl: ctrl}
+	mock.recorder = &MockClusterSummaryWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClusterSummaryWriter) EXPECT() *MockClusterSummaryWriterMockRecorder {
+	return m.recorder
+}
+
+// WriteClusterSummary mocks base method.
+func (m *MockClusterSummaryWriter) WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteClusterSummary", ctx, summary)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteClusterSummary indicates an expected call of WriteClusterSummary.
+func (mr *MockClusterSummaryWriterMockRecorder) WriteClusterSummary(ctx, summary any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteClusterSummary", reflect.TypeOf((*MockClusterSummaryWriter)(nil).WriteClusterSummary), ctx, summary)
+}
+
+// MockClusterChangeWriter is a mock of ClusterChangeWriter interface.
+type MockClusterChangeWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockClusterChangeWriterMockRecorder
+	isgomock struct{}
+}
+
+// MockClusterChangeWriterMockRecorder is the mock recorder for MockClusterChangeWriter.
+type MockClusterChangeWriterMockRecorder struct {
+	mock *MockClusterChangeWriter
+}
+
+// NewMockClusterChangeWriter creates a new mock instance.
+func NewMockClusterChangeWriter(ctrl *gomock.Controller) *MockClusterChangeWriter {
+	mock := &MockClusterChangeWriter{ctrl: ctrl}
+	mock.recorder = &MockClusterChangeWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClusterChangeWriter) EXPECT() *MockClusterChangeWriterMockRecorder {
+	return m.recorder
+}
+
+// WriteClusterChange mocks base method.
+func (m *MockClusterChangeWriter) WriteClusterChange(ctx context.Context, change entity.ClusterChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteClusterChange", ctx, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteClusterChange indicates an expected call of WriteClusterChange.
+func (mr *MockClusterChangeWriterMockRecorder) WriteClusterChange(ctx, change any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteClusterChange", reflect.TypeOf((*MockClusterChangeWriter)(nil).WriteClusterChange), ctx, change)
+}
+
 // MockProjectionWriter is a mock of ProjectionWriter interface.
 type MockProjectionWriter struct {
 	ctrl     *gomock.Controller
@@ -363,6 +662,34 @@ func (m *MockProjectionWriter) EXPECT() *MockProjectionWriterMockRecorder {
 	return m.recorder
 }
 
+// WriteClusterChange mocks base method.
+func (m *MockProjectionWriter) WriteClusterChange(ctx context.Context, change entity.ClusterChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteClusterChange", ctx, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteClusterChange indicates an expected call of WriteClusterChange.
+func (mr *MockProjectionWriterMockRecorder) WriteClusterChange(ctx, change any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteClusterChange", reflect.TypeOf((*MockProjectionWriter)(nil).WriteClusterChange), ctx, change)
+}
+
+// WriteClusterSummary mocks base method.
+func (m *MockProjectionWriter) WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteClusterSummary", ctx, summary)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteClusterSummary indicates an expected call of WriteClusterSummary.
+func (mr *MockProjectionWriterMockRecorder) WriteClusterSummary(ctx, summary any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteClusterSummary", reflect.TypeOf((*MockProjectionWriter)(nil).WriteClusterSummary), ctx, summary)
+}
+
 // WriteProjectedClusterEvent mocks base method.
 func (m *MockProjectionWriter) WriteProjectedClusterEvent(ctx context.Context, event entity.ProjectedClusterEvent) error {
 	m.ctrl.T.Helper()
@@ -391,6 +718,20 @@ func (mr *MockProjectionWriterMockRecorder) WriteProjectedClusterState(ctx, stat
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProjectedClusterState", reflect.TypeOf((*MockProjectionWriter)(nil).WriteProjectedClusterState), ctx, state)
 }
 
+// WriteProjectedHostState mocks base method.
+func (m *MockProjectionWriter) WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteProjectedHostState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteProjectedHostState indicates an expected call of WriteProjectedHostState.
+func (mr *MockProjectionWriterMockRecorder) WriteProjectedHostState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteProjectedHostState", reflect.TypeOf((*MockProjectionWriter)(nil).WriteProjectedHostState), ctx, state)
+}
+
 // WriteProjectedInfraEnv mocks base method.
 func (m *MockProjectionWriter) WriteProjectedInfraEnv(ctx context.Context, infraEnv entity.ProjectedInfraEnv) error {
 	m.ctrl.T.Helper()
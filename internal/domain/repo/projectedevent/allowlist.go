@@ -0,0 +1,224 @@
+package projectedevent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+const arraySuffix = "[]"
+
+// Allowlist lists the fields exported by projection type, nil means every field is exported.
+//
+// Fields are dot separated paths, e.g. hosts[].host_inventory: a field exports its whole subtree,
+// arrays are filtered element by element ("hosts[]" and "hosts" are equivalent).
+type Allowlist struct {
+	Events    []string
+	Clusters  []string
+	InfraEnvs []string
+	Hosts     []string
+	Summaries []string
+	Changes   []string
+}
+
+// fieldNode is a node of the allowlist tree, a leaf exports its whole subtree
+type fieldNode map[string]fieldNode
+
+func newFieldTree(fields []string) fieldNode {
+	if fields == nil {
+		return nil
+	}
+
+	root := fieldNode{}
+
+	for _, field := range fields {
+		node := root
+
+		for _, part := range strings.Split(field, ".") {
+			part = strings.TrimSuffix(part, arraySuffix)
+
+			child, found := node[part]
+			if !found {
+				child = fieldNode{}
+				node[part] = child
+			}
+
+			node = child
+		}
+	}
+
+	return root
+}
+
+// FieldFilter restricts payloads to fields, like the allowlist writer but without counting the dropped fields.
+// nil fields keeps every field.
+func FieldFilter(fields []string) func(payload map[string]interface{}) map[string]interface{} {
+	tree := newFieldTree(fields)
+
+	return func(payload map[string]interface{}) map[string]interface{} {
+		if tree == nil {
+			return payload
+		}
+
+		return filterMap(tree, payload, "", make(map[string]struct{}))
+	}
+}
+
+// AllowlistWriter drops the fields outside of the allowlist before writing the projections.
+// Dropped fields are counted and logged once per field.
+type AllowlistWriter struct {
+	inner repo.ProjectionWriter
+
+	events    fieldNode
+	clusters  fieldNode
+	infraEnvs fieldNode
+	hosts     fieldNode
+	summaries fieldNode
+	changes   fieldNode
+
+	counter *prometheus.CounterVec
+	logged  *sync.Map
+}
+
+func NewAllowlistWriter(inner repo.ProjectionWriter, allowlist Allowlist, registry prometheus.Registerer, config pipeline.MetricsConfig) (AllowlistWriter, error) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "unknown_fields_total",
+		Help:      "Number of fields dropped because they are not in the allowlist, by projection type and field.",
+	}, []string{"type", "field"})
+
+	err := registry.Register(counter)
+	if err != nil {
+		return AllowlistWriter{}, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	ret := AllowlistWriter{
+		inner:     inner,
+		events:    newFieldTree(allowlist.Events),
+		clusters:  newFieldTree(allowlist.Clusters),
+		infraEnvs: newFieldTree(allowlist.InfraEnvs),
+		hosts:     newFieldTree(allowlist.Hosts),
+		summaries: newFieldTree(allowlist.Summaries),
+		changes:   newFieldTree(allowlist.Changes),
+		counter:   counter,
+		logged:    &sync.Map{},
+	}
+
+	return ret, nil
+}
+
+func (a AllowlistWriter) WriteProjectedClusterEvent(ctx context.Context, event entity.ProjectedClusterEvent) error {
+	event.Payload = a.filter(eventTypeEvents, a.events, event.Payload)
+
+	return a.inner.WriteProjectedClusterEvent(ctx, event)
+}
+
+func (a AllowlistWriter) WriteProjectedClusterState(ctx context.Context, state entity.ProjectedClusterState) error {
+	state.Payload = a.filter(eventTypeClusters, a.clusters, state.Payload)
+
+	return a.inner.WriteProjectedClusterState(ctx, state)
+}
+
+func (a AllowlistWriter) WriteProjectedInfraEnv(ctx context.Context, infraEnv entity.ProjectedInfraEnv) error {
+	infraEnv.Payload = a.filter(eventTypeInfraEnvs, a.infraEnvs, infraEnv.Payload)
+
+	return a.inner.WriteProjectedInfraEnv(ctx, infraEnv)
+}
+
+func (a AllowlistWriter) WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error {
+	state.Payload = a.filter(eventTypeHosts, a.hosts, state.Payload)
+
+	return a.inner.WriteProjectedHostState(ctx, state)
+}
+
+func (a AllowlistWriter) WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error {
+	summary.Payload = a.filter(eventTypeSummaries, a.summaries, summary.Payload)
+
+	return a.inner.WriteClusterSummary(ctx, summary)
+}
+
+func (a AllowlistWriter) WriteClusterChange(ctx context.Context, change entity.ClusterChange) error {
+	change.Payload = a.filter(eventTypeChanges, a.changes, change.Payload)
+
+	return a.inner.WriteClusterChange(ctx, change)
+}
+
+func (a AllowlistWriter) filter(eventType string, tree fieldNode, payload map[string]interface{}) map[string]interface{} {
+	if tree == nil {
+		return payload
+	}
+
+	unknown := make(map[string]struct{})
+
+	ret := filterMap(tree, payload, "", unknown)
+
+	if len(unknown) == 0 {
+		return ret
+	}
+
+	newFields := make([]string, 0)
+
+	for field := range unknown {
+		a.counter.WithLabelValues(strings.TrimPrefix(eventType, "."), field).Inc()
+
+		_, alreadyLogged := a.logged.LoadOrStore(eventType+field, struct{}{})
+		if !alreadyLogged {
+			newFields = append(newFields, field)
+		}
+	}
+
+	if len(newFields) > 0 {
+		sort.Strings(newFields)
+		log.Logger().V(1).Info("Unknown fields dropped from projection", "type", strings.TrimPrefix(eventType, "."), "fields", newFields)
+	}
+
+	return ret
+}
+
+// filterMap returns a copy of m restricted to the fields in tree, dropped fields are added to unknown
+func filterMap(tree fieldNode, m map[string]interface{}, prefix string, unknown map[string]struct{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(tree))
+
+	for key, value := range m {
+		node, found := tree[key]
+		if !found {
+			unknown[prefix+key] = struct{}{}
+
+			continue
+		}
+
+		ret[key] = filterValue(node, value, prefix+key, unknown)
+	}
+
+	return ret
+}
+
+func filterValue(node fieldNode, value interface{}, field string, unknown map[string]struct{}) interface{} {
+	if len(node) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return filterMap(node, v, field+".", unknown)
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+
+		for i, item := range v {
+			ret[i] = filterValue(node, item, field+arraySuffix, unknown)
+		}
+
+		return ret
+	default:
+		return value
+	}
+}
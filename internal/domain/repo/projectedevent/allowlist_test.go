@@ -0,0 +1,101 @@
+package projectedevent_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/projectedevent"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func TestAllowlistWriter(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	inner := mock.NewMockProjectionWriter(ctrl)
+	registry := prometheus.NewRegistry()
+
+	writer, err := projectedevent.NewAllowlistWriter(inner, projectedevent.Allowlist{
+		Clusters: []string{"id", "hosts[].id", "hosts[].host_inventory"},
+	}, registry, pipeline.MetricsConfig{Namespace: "test"})
+	require.NoError(t, err, "failed to create writer")
+
+	hosts := []interface{}{
+		map[string]interface{}{
+			"id":             "host-1",
+			"host_inventory": map[string]interface{}{"cpu": "x86"},
+			"new_field":      "value",
+		},
+	}
+
+	state := entity.ProjectedClusterState{
+		ID: "abc",
+		Payload: map[string]interface{}{
+			"id":        "cluster-1",
+			"secret":    "value",
+			"hosts":     hosts,
+			"timestamp": "now",
+		},
+	}
+
+	inner.EXPECT().WriteProjectedClusterState(gomock.Any(), entity.ProjectedClusterState{
+		ID: "abc",
+		Payload: map[string]interface{}{
+			"id": "cluster-1",
+			"hosts": []interface{}{
+				map[string]interface{}{
+					"id":             "host-1",
+					"host_inventory": map[string]interface{}{"cpu": "x86"},
+				},
+			},
+		},
+	}).Return(nil)
+
+	err = writer.WriteProjectedClusterState(context.Background(), state)
+	require.NoError(t, err, "failed to write cluster state")
+
+	// Original payload is not modified
+	assert.Contains(t, hosts[0], "new_field")
+
+	// Projection types without allowlist are not filtered
+	event := entity.ProjectedClusterEvent{ID: "def", Payload: map[string]interface{}{"message": "hello"}}
+	inner.EXPECT().WriteProjectedClusterEvent(gomock.Any(), event).Return(nil)
+
+	err = writer.WriteProjectedClusterEvent(context.Background(), event)
+	require.NoError(t, err, "failed to write event")
+
+	expected := `
+# HELP test_unknown_fields_total Number of fields dropped because they are not in the allowlist, by projection type and field.
+# TYPE test_unknown_fields_total counter
+test_unknown_fields_total{field="hosts[].new_field",type="clusters"} 1
+test_unknown_fields_total{field="secret",type="clusters"} 1
+test_unknown_fields_total{field="timestamp",type="clusters"} 1
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+	assert.NoError(t, err, "unexpected metrics")
+}
+
+func TestFieldFilter(t *testing.T) {
+	t.Parallel()
+
+	payload := map[string]interface{}{
+		"id":    "cluster-1",
+		"hosts": []interface{}{map[string]interface{}{"id": "host-1", "secret": "value"}},
+	}
+
+	filter := projectedevent.FieldFilter([]string{"hosts[].id"})
+	assert.Equal(t, map[string]interface{}{
+		"hosts": []interface{}{map[string]interface{}{"id": "host-1"}},
+	}, filter(payload))
+
+	assert.Equal(t, payload, projectedevent.FieldFilter(nil)(payload), "nil fields keeps every field")
+}
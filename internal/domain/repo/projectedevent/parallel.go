@@ -60,3 +60,45 @@ func (p ParallelWriter) WriteProjectedInfraEnv(ctx context.Context, infraEnv ent
 
 	return group.Wait()
 }
+
+func (p ParallelWriter) WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	for _, w := range p.writers {
+		writer := w
+
+		group.Go(func() error {
+			return writer.WriteProjectedHostState(ctx, state)
+		})
+	}
+
+	return group.Wait()
+}
+
+func (p ParallelWriter) WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	for _, w := range p.writers {
+		writer := w
+
+		group.Go(func() error {
+			return writer.WriteClusterSummary(ctx, summary)
+		})
+	}
+
+	return group.Wait()
+}
+
+func (p ParallelWriter) WriteClusterChange(ctx context.Context, change entity.ClusterChange) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	for _, w := range p.writers {
+		writer := w
+
+		group.Go(func() error {
+			return writer.WriteClusterChange(ctx, change)
+		})
+	}
+
+	return group.Wait()
+}
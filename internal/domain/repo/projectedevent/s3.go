@@ -10,26 +10,58 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
 const (
-	keyTemplate = "<prefix><eventType>/<year>-<month>-<day>/<id>.ndjson"
+	keyTemplate     = "<prefix><eventType>/<year>-<month>-<day>/<id>.ndjson"
+	lateKeyTemplate = "<prefix>late/<year>-<month>-<day>/<eventType>/<id>.ndjson"
+
+	lateMetadataKey = "late"
+
+	// streamPrefix is prepended to the stream of the projections in their key
+	streamPrefix = "."
 
 	eventTypeEvents    = ".events"
 	eventTypeClusters  = ".clusters"
 	eventTypeInfraEnvs = ".infra_envs"
+	eventTypeHosts     = ".hosts"
+	eventTypeSummaries = ".cluster_summaries"
+	eventTypeChanges   = ".cluster_changes"
 
 	categoryInvalidKey    = "s3_invalid_key"
 	categoryInternalError = "s3_internal_error"
 	categoryS3ClientError = "s3_client"
+
+	stageS3OutputPrefix = "s3_output_"
 )
 
 var (
-	rxHexa        = regexp.MustCompile("^[0-9a-f].*")
-	errInvalidKey = errors.New("invalid key")
+	rxHexa           = regexp.MustCompile("^[0-9a-f].*")
+	errInvalidKey    = errors.New("invalid key")
+	errMissingStream = errors.New("missing stream")
+)
+
+// LateRouting is the way projections of an already closed day are written.
+//
+// The routing is decided when the projection is written: a projection written again after the closing of its day,
+// e.g. a debounced or reprocessed cluster state, is stored under both the normal and the late key (or tagged late),
+// readers dedupe them by id.
+type LateRouting string
+
+const (
+	// LateRoutingNone writes late projections like the others
+	LateRoutingNone LateRouting = "none"
+	// LateRoutingPrefix writes late projections under <prefix>late/<day>/<eventType>/
+	LateRoutingPrefix LateRouting = "prefix"
+	// LateRoutingMetadata tags late projections with the late=true object metadata
+	LateRoutingMetadata LateRouting = "metadata"
 )
 
 type S3Writer struct {
@@ -37,6 +69,40 @@ type S3Writer struct {
 
 	bucket string
 	prefix string
+
+	lateRouting LateRouting
+	lateStreams map[string]struct{}
+	schedule    late.Schedule
+	clock       clockwork.Clock
+
+	metrics *S3Metrics             // nil when disabled
+	stages  *pipeline.StageMetrics // nil when disabled
+}
+
+// S3Metrics measures the size of the writes by bucket, it is shared by the writers of every output.
+// Their duration is measured by the s3_output_<bucket> stage, see WithStageMetrics.
+type S3Metrics struct {
+	size *prometheus.HistogramVec
+}
+
+func NewS3Metrics(registry prometheus.Registerer, config pipeline.MetricsConfig) (*S3Metrics, error) {
+	size := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "write_size_bytes",
+		Help:      "Size of the written projections, by bucket.",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 10), // 256B -> 64MiB
+	}, []string{"bucket"})
+
+	err := registry.Register(size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	ret := &S3Metrics{
+		size: size,
+	}
+
+	return ret, nil
 }
 
 func NewS3Writer(s3client *s3.Client, bucket string, prefix string) S3Writer {
@@ -47,27 +113,76 @@ func NewS3Writer(s3client *s3.Client, bucket string, prefix string) S3Writer {
 	}
 }
 
+// WithLateRouting routes the projections of streams received after the closing of their day, see late.Schedule.
+// streams are the outputs of the event types counted as late, see processing.LateStreams.
+func (s S3Writer) WithLateRouting(routing LateRouting, streams map[string]struct{}, schedule late.Schedule, clock clockwork.Clock) (S3Writer, error) {
+	switch routing {
+	case LateRoutingNone, LateRoutingPrefix, LateRoutingMetadata:
+	default:
+		return s, fmt.Errorf("unknown late routing: %s", routing)
+	}
+
+	s.lateRouting = routing
+	s.lateStreams = streams
+	s.schedule = schedule
+	s.clock = clock
+
+	return s, nil
+}
+
+// WithMetrics measures the size of the writes to the bucket
+func (s S3Writer) WithMetrics(metrics *S3Metrics) S3Writer {
+	s.metrics = metrics
+
+	return s
+}
+
+// WithStageMetrics measures the writes as the s3_output_<bucket> stage
+func (s S3Writer) WithStageMetrics(stages *pipeline.StageMetrics) S3Writer {
+	s.stages = stages
+
+	return s
+}
+
 func (s S3Writer) WriteProjectedClusterEvent(ctx context.Context, event entity.ProjectedClusterEvent) error {
-	return s.putObject(ctx, eventTypeEvents, entity.Projection(event))
+	return s.putObject(ctx, entity.Projection(event))
 }
 
 func (s S3Writer) WriteProjectedClusterState(ctx context.Context, state entity.ProjectedClusterState) error {
-	return s.putObject(ctx, eventTypeClusters, entity.Projection(state))
+	return s.putObject(ctx, entity.Projection(state))
 }
 
 func (s S3Writer) WriteProjectedInfraEnv(ctx context.Context, infraEnv entity.ProjectedInfraEnv) error {
-	return s.putObject(ctx, eventTypeInfraEnvs, entity.Projection(infraEnv))
+	return s.putObject(ctx, entity.Projection(infraEnv))
+}
+
+func (s S3Writer) WriteProjectedHostState(ctx context.Context, state entity.ProjectedHostState) error {
+	return s.putObject(ctx, entity.Projection(state))
+}
+
+func (s S3Writer) WriteClusterSummary(ctx context.Context, summary entity.ClusterSummary) error {
+	return s.putObject(ctx, entity.Projection(summary))
+}
+
+func (s S3Writer) WriteClusterChange(ctx context.Context, change entity.ClusterChange) error {
+	return s.putObject(ctx, entity.Projection(change))
 }
 
-func (s S3Writer) putObject(ctx context.Context, eventType string, obj entity.Projection) error {
+func (s S3Writer) putObject(ctx context.Context, obj entity.Projection) error {
+	if obj.Stream == "" {
+		return common.NewErrProcessingError(errMissingStream, categoryInvalidKey, nil, "projection has no output stream")
+	}
+
 	// Marshal Payload
 	b, err := json.Marshal(obj.Payload)
 	if err != nil {
 		return common.NewErrProcessingError(err, categoryInternalError, nil, "failed to marshal payload")
 	}
 
+	isLate := s.isLate(obj)
+
 	// Compute object key
-	key, err := s.computeObjectKey(eventType, obj)
+	key, err := s.computeObjectKey(streamPrefix+obj.Stream, obj, isLate && s.lateRouting == LateRoutingPrefix)
 	if err != nil {
 		return err
 	}
@@ -79,7 +194,11 @@ func (s S3Writer) putObject(ctx context.Context, eventType string, obj entity.Pr
 		Body:   bytes.NewReader(b),
 	}
 
-	_, err = s.s3client.PutObject(ctx, params)
+	if isLate && s.lateRouting == LateRoutingMetadata {
+		params.Metadata = map[string]string{lateMetadataKey: "true"}
+	}
+
+	err = s.write(ctx, params, len(b))
 	if err != nil {
 		return common.NewErrProcessingError(err, categoryS3ClientError, nil, "failed to put object")
 	}
@@ -87,7 +206,33 @@ func (s S3Writer) putObject(ctx context.Context, eventType string, obj entity.Pr
 	return nil
 }
 
-func (s S3Writer) computeObjectKey(eventType string, obj entity.Projection) (string, error) {
+func (s S3Writer) write(ctx context.Context, params *s3.PutObjectInput, size int) error {
+	end := s.stages.Start(stageS3OutputPrefix + s.bucket)
+
+	_, err := s.s3client.PutObject(ctx, params)
+
+	end(err)
+
+	if s.metrics != nil && err == nil {
+		s.metrics.size.WithLabelValues(s.bucket).Observe(float64(size))
+	}
+
+	return err
+}
+
+func (s S3Writer) isLate(obj entity.Projection) bool {
+	if s.lateRouting == "" || s.lateRouting == LateRoutingNone {
+		return false
+	}
+
+	if _, ok := s.lateStreams[obj.Stream]; !ok {
+		return false
+	}
+
+	return s.schedule.IsLate(obj.Timestamp, s.clock.Now())
+}
+
+func (s S3Writer) computeObjectKey(eventType string, obj entity.Projection, isLate bool) (string, error) {
 	if !rxHexa.MatchString(obj.ID) {
 		return "", common.NewErrProcessingError(errInvalidKey, categoryInvalidKey, nil, "last part of the key doesn't start by 0-9a-z")
 	}
@@ -101,5 +246,9 @@ func (s S3Writer) computeObjectKey(eventType string, obj entity.Projection) (str
 		"<id>", obj.ID,
 	)
 
+	if isLate {
+		return template.Replace(lateKeyTemplate), nil
+	}
+
 	return template.Replace(keyTemplate), nil
 }
@@ -1,12 +1,16 @@
 package projectedevent
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
 )
 
 // Not part of the contract, but the part of the key after the final '/' is supposed to start by [0-9a-f]
@@ -23,7 +27,7 @@ func FuzzComputeObjectKey(f *testing.F) {
 		_, err := repo.computeObjectKey("type", entity.Projection{
 			ID:        id,
 			Timestamp: now,
-		})
+		}, false)
 
 		if id == "" {
 			assert.Error(t, err, "test should failed with empty entry")
@@ -88,7 +92,7 @@ func TestComputeObjectKey(t *testing.T) {
 		key, err := repo.computeObjectKey("custom", entity.Projection{
 			ID:        tc.id,
 			Timestamp: tc.ts,
-		})
+		}, false)
 
 		if tc.shouldFail {
 			assert.Error(t, err, "id is supposed to generate an invalid key")
@@ -99,3 +103,46 @@ func TestComputeObjectKey(t *testing.T) {
 		assert.Equal(t, tc.expect, key)
 	}
 }
+
+func TestLateRouting(t *testing.T) {
+	t.Parallel()
+
+	clock := clockwork.NewFakeClockAt(time.Date(2025, 3, 5, 15, 0, 0, 0, time.UTC))
+	closedDay := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	openDay := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	streams := map[string]struct{}{"events": {}, "clusters": {}}
+
+	projection := func(stream string, ts time.Time) entity.Projection {
+		return entity.Projection{ID: "abcdef", Timestamp: ts, Stream: stream}
+	}
+
+	repo, err := S3Writer{prefix: "prefix/"}.WithLateRouting(LateRoutingPrefix, streams, late.Schedule{}, clock)
+	require.NoError(t, err, "failed to configure late routing")
+
+	assert.True(t, repo.isLate(projection("clusters", closedDay)))
+	assert.False(t, repo.isLate(projection("clusters", openDay)))
+
+	// Only the late streams are routed
+	assert.True(t, repo.isLate(projection("events", closedDay)))
+	assert.False(t, repo.isLate(projection("hosts", closedDay)))
+	assert.False(t, repo.isLate(projection("cluster_summaries", closedDay)))
+
+	key, err := repo.computeObjectKey(".clusters", projection("clusters", closedDay), true)
+	require.NoError(t, err, "failed to compute key")
+	assert.Equal(t, "prefix/late/2025-03-04/.clusters/abcdef.ndjson", key)
+
+	// Late data isn't tracked without routing
+	repo, err = S3Writer{}.WithLateRouting(LateRoutingNone, streams, late.Schedule{}, clock)
+	require.NoError(t, err, "failed to configure late routing")
+	assert.False(t, repo.isLate(projection("clusters", closedDay)))
+
+	_, err = S3Writer{}.WithLateRouting("unknown", streams, late.Schedule{}, clock)
+	assert.Error(t, err, "unknown routing should be rejected")
+}
+
+func TestPutObjectWithoutStream(t *testing.T) {
+	t.Parallel()
+
+	err := S3Writer{}.putObject(context.Background(), entity.Projection{ID: "abcdef", Timestamp: time.Now()})
+	assert.ErrorIs(t, err, errMissingStream)
+}
@@ -0,0 +1,156 @@
+package projectedevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+)
+
+const (
+	dayPrefixTemplate     = "<prefix><eventType>/<year>-<month>-<day>/"
+	lateDayPrefixTemplate = "<prefix>late/<year>-<month>-<day>/<eventType>/"
+)
+
+type S3Reader struct {
+	s3client *s3.Client
+
+	bucket string
+	prefix string
+}
+
+func NewS3Reader(s3client *s3.Client, bucket string, prefix string) S3Reader {
+	return S3Reader{
+		s3client: s3client,
+		bucket:   bucket,
+		prefix:   prefix,
+	}
+}
+
+// ReadProjectedClusterStates calls fn for every cluster state stored between from and to (both days included).
+// Objects are read day by day, including the late ones routed by prefix. No ordering is guaranteed within a day.
+func (s S3Reader) ReadProjectedClusterStates(ctx context.Context, from, to time.Time, fn func(entity.ProjectedClusterState) error) error {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+
+	for !day.After(to) {
+		err := s.readDay(ctx, eventTypeClusters, day, func(obj entity.Projection) error {
+			return fn(entity.ProjectedClusterState(obj))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", day.Format(time.DateOnly), err)
+		}
+
+		day = day.Add(24 * time.Hour)
+	}
+
+	return nil
+}
+
+func (s S3Reader) readDay(ctx context.Context, eventType string, day time.Time, fn func(entity.Projection) error) error {
+	for _, t := range []string{dayPrefixTemplate, lateDayPrefixTemplate} {
+		err := s.readPrefix(ctx, s.computeDayPrefix(t, eventType, day), fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s S3Reader) readPrefix(ctx context.Context, prefix string, fn func(entity.Projection) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.s3client, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+
+			projection, err := s.getObject(ctx, *obj.Key)
+			if err != nil {
+				return err
+			}
+
+			if projection.Timestamp.IsZero() && obj.LastModified != nil {
+				projection.Timestamp = *obj.LastModified
+			}
+
+			err = fn(projection)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s S3Reader) getObject(ctx context.Context, key string) (entity.Projection, error) {
+	ret := entity.Projection{
+		ID: strings.TrimSuffix(path.Base(key), path.Ext(key)),
+	}
+
+	resp, err := s.s3client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return ret, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+
+	defer resp.Body.Close()
+
+	buf := bytes.Buffer{}
+
+	_, err = buf.ReadFrom(resp.Body)
+	if err != nil {
+		return ret, fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	// Keep numbers as they were written
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+
+	err = decoder.Decode(&ret.Payload)
+	if err != nil {
+		return ret, fmt.Errorf("failed to unmarshal object %s: %w", key, err)
+	}
+
+	// Projections are written with the updated_at field normalized
+	updatedAt, ok := ret.Payload["updated_at"].(string)
+	if ok {
+		ts, err := time.Parse(time.RFC3339Nano, updatedAt)
+		if err == nil {
+			ret.Timestamp = ts
+		}
+	}
+
+	return ret, nil
+}
+
+func (s S3Reader) computeDayPrefix(prefixTemplate string, eventType string, day time.Time) string {
+	template := strings.NewReplacer(
+		"<prefix>", s.prefix,
+		"<eventType>", eventType,
+		"<year>", fmt.Sprintf("%04d", day.Year()),
+		"<month>", fmt.Sprintf("%02d", day.Month()),
+		"<day>", fmt.Sprintf("%02d", day.Day()),
+	)
+
+	return template.Replace(prefixTemplate)
+}
@@ -0,0 +1,19 @@
+package rawevent
+
+import "time"
+
+// RawEvent is a consumed kafka record, as received
+type RawEvent struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Headers   []Header
+	Payload   []byte
+}
+
+type Header struct {
+	Key   []byte
+	Value []byte
+}
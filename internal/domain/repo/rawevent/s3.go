@@ -0,0 +1,110 @@
+package rawevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+)
+
+const (
+	// Same record, same key: a record consumed twice (e.g. after a rebalancing) is archived once
+	keyTemplate = "<prefix><year>/<month>/<day>/<topic>/<partition>-<offset>.json"
+
+	categoryS3ClientError = "s3_client"
+)
+
+var ErrNilEvent = errors.New("nil event")
+
+// S3Writer archives the consumed records, partitioned by record date
+type S3Writer struct {
+	s3client *s3.Client
+
+	bucket string
+	prefix string
+}
+
+func NewS3Writer(s3client *s3.Client, bucket string, prefix string) S3Writer {
+	return S3Writer{
+		s3client: s3client,
+		bucket:   bucket,
+		prefix:   prefix,
+	}
+}
+
+func (r S3Writer) WriteRawEvent(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	if msg == nil {
+		return ErrNilEvent
+	}
+
+	// Marshal RawEvent
+	b, err := json.Marshal(createRawEvent(msg))
+	if err != nil {
+		return fmt.Errorf("failed to marshal local model: %w", err)
+	}
+
+	// Compute object key
+	key := r.computeObjectKey(msg)
+
+	// Write file
+	params := &s3.PutObjectInput{
+		Bucket: &r.bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(b),
+	}
+
+	_, err = r.s3client.PutObject(ctx, params)
+	if err != nil {
+		return common.NewRetryableErrProcessingError(err, categoryS3ClientError, nil, "failed to write in s3")
+	}
+
+	return nil
+}
+
+func createRawEvent(msg *sarama.ConsumerMessage) RawEvent {
+	ret := RawEvent{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Key:       msg.Key,
+		Headers:   make([]Header, 0, len(msg.Headers)),
+		Payload:   msg.Value,
+	}
+
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+
+		ret.Headers = append(ret.Headers, Header{
+			Key:   h.Key,
+			Value: h.Value,
+		})
+	}
+
+	return ret
+}
+
+func (r S3Writer) computeObjectKey(msg *sarama.ConsumerMessage) string {
+	ts := msg.Timestamp.UTC()
+
+	template := strings.NewReplacer(
+		"<prefix>", r.prefix,
+		"<year>", fmt.Sprintf("%04d", ts.Year()),
+		"<month>", fmt.Sprintf("%02d", ts.Month()),
+		"<day>", fmt.Sprintf("%02d", ts.Day()),
+		"<topic>", msg.Topic,
+		"<partition>", fmt.Sprintf("%d", msg.Partition),
+		"<offset>", fmt.Sprintf("%d", msg.Offset),
+	)
+
+	return template.Replace(keyTemplate)
+}
@@ -0,0 +1,60 @@
+package rawevent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeObjectKey(t *testing.T) {
+	t.Parallel()
+
+	repo := S3Writer{prefix: "raw/"}
+
+	key := repo.computeObjectKey(&sarama.ConsumerMessage{
+		Topic:     "events",
+		Partition: 3,
+		Offset:    1234,
+		Timestamp: time.Date(2025, 3, 3, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600)),
+	})
+
+	assert.Equal(t, "raw/2025/03/04/events/3-1234.json", key)
+}
+
+func TestCreateRawEvent(t *testing.T) {
+	t.Parallel()
+
+	msg := &sarama.ConsumerMessage{
+		Topic:     "events",
+		Partition: 3,
+		Offset:    1234,
+		Timestamp: time.Date(2025, 3, 3, 23, 30, 0, 0, time.UTC),
+		Key:       []byte("cluster-1"),
+		Value:     []byte(`{"name":"Event"`), // Invalid json is archived too
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("version"), Value: []byte("v1")},
+			nil,
+		},
+	}
+
+	b, err := json.Marshal(createRawEvent(msg))
+	require.NoError(t, err, "failed to marshal raw event")
+
+	res := RawEvent{}
+	err = json.Unmarshal(b, &res)
+	require.NoError(t, err, "failed to unmarshal raw event")
+
+	assert.Equal(t, RawEvent{
+		Topic:     "events",
+		Partition: 3,
+		Offset:    1234,
+		Timestamp: msg.Timestamp,
+		Key:       []byte("cluster-1"),
+		Headers:   []Header{{Key: []byte("version"), Value: []byte("v1")}},
+		Payload:   []byte(`{"name":"Event"`),
+	}, res)
+}
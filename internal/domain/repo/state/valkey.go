@@ -0,0 +1,151 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+const (
+	categoryValkeyClientError = "valkey_client"
+
+	keySeparator = ":"
+
+	stageValkeyGet = "valkey_get"
+	stageValkeySet = "valkey_set"
+)
+
+// compareAndSetScript sets the state if it is unchanged.
+// KEYS[1]: state key, ARGV: previously found ("true" / "false"), previous value, value, ttl (ms, 0 means no expiration).
+// Returns 1 once set, 0 when the state has changed.
+var compareAndSetScript = valkey.NewLuaScript(`
+local current = redis.call('GET', KEYS[1])
+if ARGV[1] == 'true' then
+	if current ~= ARGV[2] then
+		return 0
+	end
+elseif current then
+	return 0
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+else
+	redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// ValkeyStore stores per cluster processing states as plain strings: <kind>:<cluster id>.
+// Host states are stored in hashes keyed by cluster id, both never collide.
+type ValkeyStore struct {
+	client valkey.Client
+	stages *pipeline.StageMetrics // nil when disabled
+}
+
+func NewValkeyStore(client valkey.Client) ValkeyStore {
+	return ValkeyStore{
+		client: client,
+	}
+}
+
+// WithStageMetrics measures the reads & writes as the valkey_get & valkey_set stages
+func (s ValkeyStore) WithStageMetrics(stages *pipeline.StageMetrics) ValkeyStore {
+	s.stages = stages
+
+	return s
+}
+
+func (s ValkeyStore) GetState(ctx context.Context, kind, clusterID string) (_ []byte, _ bool, err error) {
+	end := s.stages.Start(stageValkeyGet)
+	defer func() { end(err) }()
+
+	command := s.client.B().Get().Key(key(kind, clusterID)).Build()
+
+	ret, err := s.client.Do(ctx, command).AsBytes()
+	if valkey.IsValkeyNil(err) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		switch {
+		case isRetryable(err):
+			return nil, false, common.NewRetryableErrProcessingError(err, categoryValkeyClientError, nil, "failed to get %s state", kind)
+		default:
+			return nil, false, common.NewErrProcessingError(err, categoryValkeyClientError, nil, "failed to get %s state", kind)
+		}
+	}
+
+	return ret, true, nil
+}
+
+// SetState stores the state, a ttl of 0 means no expiration
+func (s ValkeyStore) SetState(ctx context.Context, kind, clusterID string, value []byte, ttl time.Duration) (err error) {
+	end := s.stages.Start(stageValkeySet)
+	defer func() { end(err) }()
+
+	builder := s.client.B().Set().Key(key(kind, clusterID)).Value(valkey.BinaryString(value))
+
+	command := builder.Build()
+	if ttl > 0 {
+		command = builder.Px(ttl).Build()
+	}
+
+	err = s.client.Do(ctx, command).Error()
+	if err != nil {
+		switch {
+		case isRetryable(err):
+			return common.NewRetryableErrProcessingError(err, categoryValkeyClientError, nil, "failed to set %s state", kind)
+		default:
+			return common.NewErrProcessingError(err, categoryValkeyClientError, nil, "failed to set %s state", kind)
+		}
+	}
+
+	return nil
+}
+
+func (s ValkeyStore) CompareAndSetState(ctx context.Context, kind, clusterID string, previous, value []byte, ttl time.Duration) (_ bool, err error) {
+	end := s.stages.Start(stageValkeySet)
+	defer func() { end(err) }()
+
+	resp := compareAndSetScript.Exec(ctx, s.client,
+		[]string{key(kind, clusterID)},
+		[]string{strconv.FormatBool(previous != nil), string(previous), string(value), strconv.FormatInt(ttl.Milliseconds(), 10)},
+	)
+
+	ret, err := resp.AsInt64()
+	if err != nil {
+		switch {
+		case isRetryable(err):
+			return false, common.NewRetryableErrProcessingError(err, categoryValkeyClientError, nil, "failed to set %s state", kind)
+		default:
+			return false, common.NewErrProcessingError(err, categoryValkeyClientError, nil, "failed to set %s state", kind)
+		}
+	}
+
+	return ret == 1, nil
+}
+
+func key(kind, clusterID string) string {
+	return kind + keySeparator + clusterID
+}
+
+func isRetryable(err error) bool {
+	// Network error
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	vErr, isValkeyError := valkey.IsValkeyErr(err)
+	if !isValkeyError {
+		return false
+	}
+
+	return vErr.IsTryAgain()
+}
@@ -0,0 +1,80 @@
+package state_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/state"
+	"github.com/openshift-assisted/ccx-exporter/internal/factory"
+)
+
+func TestValkeyStore(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "quay.io/sclorg/valkey-7-c10s:bf91acf0827dc5db216164aafe3d34beb245dcec",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections tcp"),
+		},
+		Started: true,
+	})
+
+	testcontainers.CleanupContainer(t, container)
+
+	require.NoError(t, err, "failed to start valkey instance")
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err, "failed to get valkey endpoint")
+
+	client, err := factory.CreateValkeyClient(ctx, config.Valkey{URL: endpoint})
+	require.NoError(t, err, "failed to create valkey client")
+
+	defer client.Close()
+
+	store := state.NewValkeyStore(client)
+
+	_, found, err := store.GetState(ctx, "kind", "cluster-1")
+	require.NoError(t, err, "failed to get missing state")
+	assert.False(t, found, "state should not exist")
+
+	err = store.SetState(ctx, "kind", "cluster-1", []byte("value"), time.Second)
+	require.NoError(t, err, "failed to set state")
+
+	value, found, err := store.GetState(ctx, "kind", "cluster-1")
+	require.NoError(t, err, "failed to get state")
+	assert.True(t, found, "state should exist")
+	assert.Equal(t, []byte("value"), value)
+
+	// Kinds are namespaced
+	_, found, err = store.GetState(ctx, "other", "cluster-1")
+	require.NoError(t, err, "failed to get state")
+	assert.False(t, found, "state of another kind should not exist")
+
+	// Conditional writes
+	set, err := store.CompareAndSetState(ctx, "kind", "cluster-1", []byte("stale"), []byte("new"), time.Second)
+	require.NoError(t, err, "failed to compare and set state")
+	assert.False(t, set, "changed state should not be replaced")
+
+	set, err = store.CompareAndSetState(ctx, "kind", "cluster-1", []byte("value"), []byte("new"), time.Second)
+	require.NoError(t, err, "failed to compare and set state")
+	assert.True(t, set, "unchanged state should be replaced")
+
+	set, err = store.CompareAndSetState(ctx, "kind", "cluster-2", nil, []byte("new"), time.Second)
+	require.NoError(t, err, "failed to compare and set state")
+	assert.True(t, set, "missing state should be set")
+
+	// Expiration
+	assert.Eventually(t, func() bool {
+		_, found, err := store.GetState(ctx, "kind", "cluster-1")
+
+		return err == nil && !found
+	}, 5*time.Second, 100*time.Millisecond, "state should expire")
+}
@@ -0,0 +1,102 @@
+package factory
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func CreateAnonymizer(conf config.Anonymization) (anonymization.Engine, error) {
+	rules := make([]anonymization.Rule, 0)
+
+	if conf.UseDefaultRules {
+		rules = append(rules, anonymization.DefaultRules()...)
+	}
+
+	for _, r := range conf.Rules {
+		rules = append(rules, anonymization.Rule{
+			Event:  r.Event,
+			Path:   r.Path,
+			Action: anonymization.Action(r.Action),
+			To:     r.To,
+			Length: r.Length,
+		})
+	}
+
+	pseudonymizer, err := createPseudonymizer(conf.Pseudonymization)
+	if err != nil {
+		return anonymization.Engine{}, err
+	}
+
+	ret, err := anonymization.NewEngine(rules, pseudonymizer)
+	if err != nil {
+		return ret, fmt.Errorf("failed to create anonymization engine: %w", err)
+	}
+
+	return ret, nil
+}
+
+func createPseudonymizer(conf config.Pseudonymization) (anonymization.Pseudonymizer, error) {
+	keys := make([]anonymization.Key, 0, len(conf.Keys))
+
+	for _, k := range conf.Keys {
+		keys = append(keys, anonymization.Key{
+			ID:        k.ID,
+			Secret:    []byte(k.Secret),
+			NotBefore: k.NotBefore,
+			NotAfter:  k.NotAfter,
+		})
+	}
+
+	ret, err := anonymization.NewPseudonymizer(anonymization.Mode(conf.Mode), keys, clockwork.NewRealClock())
+	if err != nil {
+		return ret, fmt.Errorf("failed to create pseudonymizer: %w", err)
+	}
+
+	return ret, nil
+}
+
+func CreateScrubber(conf config.Scrubbing, registry prometheus.Registerer) (anonymization.Scrubber, error) {
+	detectors := make([]anonymization.Detector, 0, len(conf.Patterns))
+
+	for _, p := range conf.Patterns {
+		pattern, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return anonymization.Scrubber{}, fmt.Errorf("invalid scrub pattern %s: %w", p.Name, err)
+		}
+
+		detectors = append(detectors, anonymization.Detector{
+			Name:        p.Name,
+			Pattern:     pattern,
+			Replacement: p.Replacement,
+		})
+	}
+
+	rules := make([]anonymization.ScrubRule, 0, len(conf.Rules))
+
+	for _, r := range conf.Rules {
+		rules = append(rules, anonymization.ScrubRule{
+			Event:     r.Event,
+			Path:      r.Path,
+			Detectors: r.Detectors,
+		})
+	}
+
+	ret, err := anonymization.NewScrubber(rules, detectors)
+	if err != nil {
+		return ret, fmt.Errorf("failed to create scrubber: %w", err)
+	}
+
+	ret, err = ret.WithMetrics(registry, pipeline.MetricsConfig{Namespace: "processing"})
+	if err != nil {
+		return ret, fmt.Errorf("failed to create scrubber metrics: %w", err)
+	}
+
+	return ret, nil
+}
@@ -15,6 +15,36 @@ import (
 )
 
 func CreateKafkaConsumer(kafkaConfig config.Kafka) (sarama.ConsumerGroup, error) {
+	conf, urls, err := createSaramaConfig(kafkaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// kafka consumer group
+	ret, err := sarama.NewConsumerGroup(urls, kafkaConfig.Consumer.Group, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka consumer group: %w", err)
+	}
+
+	return ret, nil
+}
+
+// CreateKafkaClient creates a client configured like the consumer group, e.g. to create other consumer groups or to get offsets
+func CreateKafkaClient(kafkaConfig config.Kafka) (sarama.Client, error) {
+	conf, urls, err := createSaramaConfig(kafkaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := sarama.NewClient(urls, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return ret, nil
+}
+
+func createSaramaConfig(kafkaConfig config.Kafka) (*sarama.Config, []string, error) {
 	conf := sarama.NewConfig()
 
 	// mandatory configuration
@@ -30,7 +60,7 @@ func CreateKafkaConsumer(kafkaConfig config.Kafka) (sarama.ConsumerGroup, error)
 	// kafka version
 	version, err := sarama.ParseKafkaVersion(kafkaConfig.Broker.Version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse kafka version: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse kafka version: %w", err)
 	}
 
 	conf.Version = version
@@ -52,13 +82,7 @@ func CreateKafkaConsumer(kafkaConfig config.Kafka) (sarama.ConsumerGroup, error)
 		conf.Net.TLS.Enable = true
 	}
 
-	// kafka consumer group
-	ret, err := sarama.NewConsumerGroup(urls, kafkaConfig.Consumer.Group, conf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kafka consumer group: %w", err)
-	}
-
-	return ret, nil
+	return conf, urls, nil
 }
 
 func computeClientID(groupID string) string {
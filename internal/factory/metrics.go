@@ -9,16 +9,40 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/version"
 )
 
-func CreatePrometheusServer(conf config.Metrics, gatherer prometheus.Gatherer) *http.Server {
+// CreatePrometheusServer serves the metrics & the additional handlers by path, e.g. the probes
+func CreatePrometheusServer(conf config.Metrics, gatherer prometheus.Gatherer, handlers map[string]http.Handler) *http.Server {
 	ret := &http.Server{Addr: fmt.Sprintf(":%v", conf.Port)}
 	ret.SetKeepAlivesEnabled(true)
 	ret.IdleTimeout = 5 * time.Second
 
 	router := http.NewServeMux()
 	router.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+
+	for path, handler := range handlers {
+		router.Handle(path, handler)
+	}
+
 	ret.Handler = router
 
 	return ret
 }
+
+// RegisterBuildInfo publishes the revision & branch of the binary as a constant gauge
+func RegisterBuildInfo(registry prometheus.Registerer) error {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "build_info",
+		Help: "Always 1, labeled by the revision and branch the binary was built from.",
+	}, []string{"revision", "branch"})
+
+	err := registry.Register(gauge)
+	if err != nil {
+		return fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	gauge.WithLabelValues(version.Revision, version.Branch).Set(1)
+
+	return nil
+}
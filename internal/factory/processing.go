@@ -3,41 +3,77 @@ package factory
 import (
 	"fmt"
 
+	"github.com/IBM/sarama"
 	"github.com/jonboulle/clockwork"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
 	"github.com/openshift-assisted/ccx-exporter/internal/processing"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
+// CreateDateParser only accepts the legacy format in strict mode
+func CreateDateParser(conf config.Dates) (processing.DateParser, error) {
+	formats := conf.Formats
+	if conf.Strict {
+		formats = []string{processing.DateFormatLegacy}
+	}
+
+	ret, err := processing.NewDateParser(formats)
+	if err != nil {
+		return ret, fmt.Errorf("failed to create date parser: %w", err)
+	}
+
+	return ret, nil
+}
+
 /*
  * DecorateProcessing decorates the processing as follow:
  *
- * panic --> count data --> count late data --> duration --> retry --> main (anonymize + ... + s3)
+ * panic --> count data --> count late data --> end to end latency --> duration --> retry --> main (anonymize + ... + s3)
+ *
+ * Each layer runs in its own span (processing.panic, processing.count, ...).
  */
-func DecorateProcessing(mainProcessing pipeline.Processing[entity.Event], registry prometheus.Registerer) (pipeline.Processing[entity.Event], error) {
-	ret := mainProcessing
+func DecorateProcessing(mainProcessing pipeline.Processing[entity.Event], schedule late.Schedule, dateParser processing.DateParser, registry prometheus.Registerer) (pipeline.Processing[entity.Event], error) {
+	ret := pipeline.NewTracingDecoratorProcessing(mainProcessing, "processing.main")
 
 	metricsConfig := pipeline.MetricsConfig{Namespace: "processing"}
 
 	ret = pipeline.NewRetryProcessing(ret, pipeline.RetryConfig{})
-	ret, err := pipeline.NewDurationMetricsDecoratorProcessing(ret, registry, clockwork.NewRealClock(), metricsConfig)
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.retry")
+
+	ret, err := pipeline.NewDurationByNameMetricsDecoratorProcessing(ret, registry, clockwork.NewRealClock(), metricsConfig, processing.EventNameLabel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create duration metrics processor: %w", err)
 	}
 
-	ret, err = processing.NewCountLateData(ret, registry, clockwork.NewRealClock(), metricsConfig)
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.duration")
+
+	ret, err = processing.NewEndToEndLatency(ret, dateParser, registry, clockwork.NewRealClock(), metricsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create end to end latency metrics processor: %w", err)
+	}
+
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.latency")
+
+	ret, err = processing.NewCountLateData(ret, schedule, dateParser, registry, clockwork.NewRealClock(), metricsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create count late event metrics processor: %w", err)
 	}
 
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.late")
+
 	ret, err = processing.NewCountData(ret, registry, metricsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create count event metrics processor: %w", err)
 	}
 
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.count")
+
 	ret = pipeline.NewPanicHandlerProcessing(ret)
+	ret = pipeline.NewTracingDecoratorProcessing(ret, "processing.panic")
 
 	return ret, nil
 }
@@ -70,3 +106,23 @@ func DecorateErrorProcessing(mainProcessing pipeline.ErrorProcessing, registry p
 
 	return ret, nil
 }
+
+/*
+ * DecorateArchive decorates the raw event archive as follow:
+ *
+ * panic --> duration --> retry --> main (s3)
+ */
+func DecorateArchive(archive pipeline.Processing[*sarama.ConsumerMessage], registry prometheus.Registerer) (pipeline.Processing[*sarama.ConsumerMessage], error) {
+	ret := archive
+
+	ret = pipeline.NewRetryProcessing(ret, pipeline.RetryConfig{})
+
+	ret, err := pipeline.NewDurationMetricsDecoratorProcessing(ret, registry, clockwork.NewRealClock(), pipeline.MetricsConfig{Namespace: "archive"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration metrics processor: %w", err)
+	}
+
+	ret = pipeline.NewPanicHandlerProcessing(ret)
+
+	return ret, nil
+}
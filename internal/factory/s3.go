@@ -13,6 +13,7 @@ import (
 
 	"github.com/openshift-assisted/ccx-exporter/internal/config"
 	"github.com/openshift-assisted/ccx-exporter/internal/log"
+	"github.com/openshift-assisted/ccx-exporter/internal/tracing"
 )
 
 func CreateS3Client(ctx context.Context, conf config.S3) (*s3.Client, error) {
@@ -39,6 +40,7 @@ func CreateS3Client(ctx context.Context, conf config.S3) (*s3.Client, error) {
 
 	ret := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
 		o.UsePathStyle = conf.UsePathStyle
+		o.APIOptions = append(o.APIOptions, tracing.AddS3Middleware)
 	})
 
 	return ret, nil
@@ -2,7 +2,12 @@ package factory
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/valkey-io/valkey-go"
 
@@ -10,10 +15,12 @@ import (
 )
 
 func CreateValkeyClient(ctx context.Context, conf config.Valkey) (valkey.Client, error) {
-	ret, err := valkey.NewClient(valkey.ClientOption{
-		InitAddress: []string{conf.URL},
-		Password:    conf.Creds.Password,
-	})
+	opts, err := createValkeyOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := valkey.NewClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create valkey client: %w", err)
 	}
@@ -22,8 +29,105 @@ func CreateValkeyClient(ctx context.Context, conf config.Valkey) (valkey.Client,
 
 	err = ret.Do(ctx, ping).Error()
 	if err != nil {
+		ret.Close()
+
 		return nil, fmt.Errorf("failed to ping valkey: %w", err)
 	}
 
+	if conf.Mode == config.ValkeyModeCluster {
+		err = checkClusterMode(ctx, ret)
+		if err != nil {
+			ret.Close()
+
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// checkClusterMode fails when the server is not a cluster: the client silently falls back to a single client
+func checkClusterMode(ctx context.Context, client valkey.Client) error {
+	info, err := client.Do(ctx, client.B().Info().Section("cluster").Build()).ToString()
+	if err != nil {
+		return fmt.Errorf("failed to get valkey cluster info: %w", err)
+	}
+
+	if !strings.Contains(info, "cluster_enabled:1") {
+		return errors.New("valkey mode is cluster but cluster support is disabled on the server")
+	}
+
+	return nil
+}
+
+func createValkeyOptions(conf config.Valkey) (valkey.ClientOption, error) {
+	ret := valkey.ClientOption{
+		InitAddress:       strings.Split(conf.URL, ","),
+		Username:          conf.Creds.Username,
+		Password:          conf.Creds.Password,
+		CacheSizeEachConn: conf.Cache.SizeEachConn,
+	}
+
+	// TLS
+	if conf.TLS.Enabled {
+		tlsConfig, err := createValkeyTLSConfig(conf.TLS)
+		if err != nil {
+			return ret, fmt.Errorf("failed to create tls config: %w", err)
+		}
+
+		ret.TLSConfig = tlsConfig
+	}
+
+	// Topology
+	switch conf.Mode {
+	case config.ValkeyModeAuto:
+	case config.ValkeyModeStandalone:
+		ret.ForceSingleClient = true
+	case config.ValkeyModeCluster:
+		// Client switches to cluster mode when CLUSTER SLOTS succeeds, the server mode is checked once connected.
+		// Every address is used as a seed
+		ret.ForceSingleClient = false
+		ret.ShuffleInit = true
+	case config.ValkeyModeSentinel:
+		if conf.Sentinel.MasterSet == "" {
+			return ret, errors.New("sentinel mode requires a master set")
+		}
+
+		ret.Sentinel = valkey.SentinelOption{
+			MasterSet: conf.Sentinel.MasterSet,
+			Username:  conf.Sentinel.Creds.Username,
+			Password:  conf.Sentinel.Creds.Password,
+			TLSConfig: ret.TLSConfig,
+		}
+	default:
+		return ret, fmt.Errorf("unexpected valkey mode %v", conf.Mode)
+	}
+
+	return ret, nil
+}
+
+func createValkeyTLSConfig(conf config.ValkeyTLS) (*tls.Config, error) {
+	ret := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         conf.ServerName,
+		InsecureSkipVerify: conf.InsecureSkipVerify, //nolint:gosec // explicit opt-in, meant for local testing
+	}
+
+	if conf.CAFile == "" {
+		return ret, nil
+	}
+
+	ca, err := os.ReadFile(conf.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca file %s: %w", conf.CAFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %s", conf.CAFile)
+	}
+
+	ret.RootCAs = pool
+
 	return ret, nil
 }
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/valkey-io/valkey-go"
+)
+
+type S3HeadBucketAPI interface {
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// ValkeyPing checks the connection to valkey
+func ValkeyPing(client valkey.Client) Check {
+	return func(ctx context.Context) error {
+		err := client.Do(ctx, client.B().Ping().Build()).Error()
+		if err != nil {
+			return fmt.Errorf("failed to ping valkey: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// S3HeadBucket checks the bucket exists & is reachable with the configured credentials
+func S3HeadBucket(client S3HeadBucketAPI, bucket string) Check {
+	return func(ctx context.Context) error {
+		_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &bucket})
+		if err != nil {
+			return fmt.Errorf("failed to head bucket %s: %w", bucket, err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check returns an error when the dependency is not healthy
+type Check func(ctx context.Context) error
+
+type Status string
+
+const (
+	StatusOK     Status = "ok"
+	StatusFailed Status = "failed"
+)
+
+// Report is the JSON body of the probes
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks"`
+}
+
+type CheckReport struct {
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Handler runs every check in parallel, answering 200 when all succeed and 503 otherwise.
+// Checks can be registered once the server is started: dependencies are created after the probes are served.
+type Handler struct {
+	timeout time.Duration
+
+	lock   sync.RWMutex
+	checks map[string]Check
+}
+
+func NewHandler(timeout time.Duration) *Handler {
+	return &Handler{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+func (h *Handler) Register(name string, check Check) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.checks[name] = check
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Run executes every check, each one being bounded by the timeout
+func (h *Handler) Run(ctx context.Context) Report {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	ret := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckReport, len(h.checks)),
+	}
+
+	var lock sync.Mutex
+
+	var wg sync.WaitGroup
+
+	for name, check := range h.checks {
+		wg.Add(1)
+
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			report := h.run(ctx, check)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			ret.Checks[name] = report
+
+			if report.Status != StatusOK {
+				ret.Status = StatusFailed
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	return ret
+}
+
+func (h *Handler) run(ctx context.Context, check Check) CheckReport {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	err := check(ctx)
+	if err != nil {
+		return CheckReport{Status: StatusFailed, Error: err.Error()}
+	}
+
+	return CheckReport{Status: StatusOK}
+}
@@ -0,0 +1,105 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/health"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	type testCase struct {
+		name           string
+		checks         map[string]health.Check
+		expectedCode   int
+		expectedReport health.Report
+	}
+
+	cases := []testCase{
+		{
+			name:         "no check",
+			expectedCode: http.StatusOK,
+			expectedReport: health.Report{
+				Status: health.StatusOK,
+				Checks: map[string]health.CheckReport{},
+			},
+		},
+		{
+			name:         "all checks succeed",
+			checks:       map[string]health.Check{"valkey": ok, "kafka": ok},
+			expectedCode: http.StatusOK,
+			expectedReport: health.Report{
+				Status: health.StatusOK,
+				Checks: map[string]health.CheckReport{
+					"valkey": {Status: health.StatusOK},
+					"kafka":  {Status: health.StatusOK},
+				},
+			},
+		},
+		{
+			name:         "one check fails",
+			checks:       map[string]health.Check{"valkey": failing, "kafka": ok},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedReport: health.Report{
+				Status: health.StatusFailed,
+				Checks: map[string]health.CheckReport{
+					"valkey": {Status: health.StatusFailed, Error: "connection refused"},
+					"kafka":  {Status: health.StatusOK},
+				},
+			},
+		},
+		{
+			name:         "one check times out",
+			checks:       map[string]health.Check{"s3-dlq": slow},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedReport: health.Report{
+				Status: health.StatusFailed,
+				Checks: map[string]health.CheckReport{
+					"s3-dlq": {Status: health.StatusFailed, Error: context.DeadlineExceeded.Error()},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := health.NewHandler(10 * time.Millisecond)
+			for name, check := range tc.checks {
+				handler.Register(name, check)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tc.expectedCode, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+			var report health.Report
+
+			err := json.Unmarshal(recorder.Body.Bytes(), &report)
+			require.NoError(t, err, "failed to decode report")
+
+			assert.Equal(t, tc.expectedReport, report)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package late
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidExpression = errors.New("invalid closing time expression")
+
+// Schedule computes the deadline of late data from cron-like closing times (UTC).
+// The previous day is closed at the last closing time of the day.
+// The zero value follows the CCX schedule: the previous day is processed for the last time at 2PM.
+type Schedule struct {
+	lastClosing time.Duration // since midnight
+	configured  bool
+}
+
+// NewSchedule parses "<minute> <hour> * * *" expressions, minute & hour being numbers or comma separated lists.
+// Day of month, month & day of week must be '*': every day is closed the same way.
+func NewSchedule(expressions []string) (Schedule, error) {
+	if len(expressions) == 0 {
+		return Schedule{}, errors.New("at least one closing time is expected")
+	}
+
+	closings := make([]time.Duration, 0, len(expressions))
+
+	for _, e := range expressions {
+		c, err := parseExpression(e)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("failed to parse %q: %w", e, err)
+		}
+
+		closings = append(closings, c...)
+	}
+
+	sort.Slice(closings, func(i, j int) bool { return closings[i] < closings[j] })
+
+	return Schedule{lastClosing: closings[len(closings)-1], configured: true}, nil
+}
+
+// Deadline returns the beginning of the oldest day not closed yet: data before it is late
+func (s Schedule) Deadline(now time.Time) time.Time {
+	lastClosing := s.lastClosing
+	if !s.configured {
+		lastClosing = 14 * time.Hour
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if now.After(today.Add(lastClosing)) {
+		return today
+	}
+
+	return today.Add(-24 * time.Hour)
+}
+
+// IsLate reports whether data of date is received after its day has been closed
+func (s Schedule) IsLate(date time.Time, now time.Time) bool {
+	return date.Before(s.Deadline(now))
+}
+
+func parseExpression(expression string) ([]time.Duration, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: 5 fields expected", errInvalidExpression)
+	}
+
+	for _, f := range fields[2:] {
+		if f != "*" {
+			return nil, fmt.Errorf("%w: only '*' is supported for day of month, month & day of week", errInvalidExpression)
+		}
+	}
+
+	minutes, err := parseList(fields[0], 59)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minute: %w", err)
+	}
+
+	hours, err := parseList(fields[1], 23)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hour: %w", err)
+	}
+
+	ret := make([]time.Duration, 0, len(minutes)*len(hours))
+
+	for _, h := range hours {
+		for _, m := range minutes {
+			ret = append(ret, time.Duration(h)*time.Hour+time.Duration(m)*time.Minute)
+		}
+	}
+
+	return ret, nil
+}
+
+func parseList(field string, maxValue int) ([]int, error) {
+	values := strings.Split(field, ",")
+	ret := make([]int, 0, len(values))
+
+	for _, v := range values {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is not a number", errInvalidExpression, v)
+		}
+
+		if i < 0 || i > maxValue {
+			return nil, fmt.Errorf("%w: %d is out of range [0, %d]", errInvalidExpression, i, maxValue)
+		}
+
+		ret = append(ret, i)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,86 @@
+package late_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
+)
+
+func TestScheduleDeadline(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := late.NewSchedule([]string{"0 2 * * *", "30 9,18 * * *"})
+	require.NoError(t, err, "failed to create schedule")
+
+	type testCase struct {
+		name     string
+		schedule late.Schedule
+		now      time.Time
+		expected time.Time
+	}
+
+	cases := []testCase{
+		{
+			name:     "default before 2PM",
+			now:      time.Date(2024, 12, 25, 13, 59, 59, 0, time.UTC),
+			expected: time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "default after 2PM",
+			now:      time.Date(2024, 12, 25, 14, 0, 1, 0, time.UTC),
+			expected: time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "before the last closing time",
+			schedule: schedule,
+			now:      time.Date(2024, 12, 25, 18, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "after the last closing time",
+			schedule: schedule,
+			now:      time.Date(2024, 12, 25, 18, 30, 1, 0, time.UTC),
+			expected: time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "not UTC",
+			schedule: schedule,
+			now:      time.Date(2024, 12, 25, 20, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+			expected: time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, c.expected, c.schedule.Deadline(c.now))
+		})
+	}
+
+	now := time.Date(2024, 12, 25, 20, 0, 0, 0, time.UTC)
+	assert.True(t, schedule.IsLate(time.Date(2024, 12, 24, 23, 59, 0, 0, time.UTC), now), "previous day is closed")
+	assert.False(t, schedule.IsLate(time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), now), "current day is open")
+}
+
+func TestNewScheduleInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expressions := range [][]string{
+		nil,
+		{"0 14 * *"},
+		{"0 14 * * 1"},
+		{"60 14 * * *"},
+		{"0 24 * * *"},
+		{"*/5 14 * * *"},
+	} {
+		_, err := late.NewSchedule(expressions)
+		assert.Errorf(t, err, "%v should be rejected", expressions)
+	}
+}
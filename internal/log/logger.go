@@ -1,22 +1,27 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/bombsimon/logrusr/v4"
 	"github.com/go-logr/logr"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/config"
 )
 
-var logger logr.Logger
+var (
+	logger     logr.Logger
+	loggerImpl *logrus.Logger
+)
 
 func Init(conf config.Logs) error {
-	loggerImpl := logrus.New()
+	loggerImpl = logrus.New()
 
-	loggerImpl.SetLevel(logrus.Level(conf.Level + int(logrus.InfoLevel)))
+	loggerImpl.SetLevel(toLogrusLevel(conf.Level))
 	loggerImpl.SetOutput(os.Stdout)
 
 	switch conf.Encoder {
@@ -38,3 +43,33 @@ func Init(conf config.Logs) error {
 func Logger() logr.Logger {
 	return logger
 }
+
+// FromContext returns the logger with the trace & span ids of the span in ctx
+func FromContext(ctx context.Context) logr.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logger
+	}
+
+	return logger.WithValues("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+}
+
+// Level returns the current verbosity, see config.Logs
+func Level() int {
+	return int(loggerImpl.GetLevel()) - int(logrus.InfoLevel)
+}
+
+// SetLevel changes the verbosity at runtime, V(n) logs are written when n <= level
+func SetLevel(level int) error {
+	if level < 0 {
+		return fmt.Errorf("unexpected negative level %d", level)
+	}
+
+	loggerImpl.SetLevel(toLogrusLevel(level))
+
+	return nil
+}
+
+func toLogrusLevel(level int) logrus.Level {
+	return logrus.Level(level + int(logrus.InfoLevel))
+}
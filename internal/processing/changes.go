@@ -0,0 +1,317 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+)
+
+const (
+	stateKindPreviousClusterState = "previous_cluster_state"
+
+	// Previous cluster states are stored as: <version byte><zstd(json(previousClusterState))>
+	// Legacy values (plain json) are still readable: they start with '{'.
+	encodingVersionZstdJSON byte = 0x01
+)
+
+// Fields changing with every cluster state, they are part of the change record instead of the diff
+var changesIgnoredFields = map[string]struct{}{
+	"cluster_state_id": {},
+	"updated_at":       {},
+}
+
+var (
+	errUnknownEncoding = errors.New("unknown encoding")
+	errChangesConflict = errors.New("previous cluster state updated concurrently")
+)
+
+// PayloadFilter restricts a payload to the exported fields, e.g. the cluster projections allowlist
+type PayloadFilter func(payload map[string]interface{}) map[string]interface{}
+
+type changes struct {
+	store        repo.StateStore
+	ttl          time.Duration
+	filter       PayloadFilter // nil keeps every field
+	maxValueSize int           // 0 means no limit
+}
+
+// previousClusterState has no payload when it was too large to be stored
+type previousClusterState struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Payload   map[string]interface{} `json:"payload,omitempty"`
+}
+
+// WithClusterChanges emits the diff between consecutive cluster states of the same cluster.
+// Cluster states are filtered before being compared: the changes only contain exported fields.
+// The previous filtered cluster state is kept compressed in store, ttl after its last update.
+// When larger than maxValueSize (0 means no limit), only its id is kept and the next change isn't emitted.
+func (m Main) WithClusterChanges(store repo.StateStore, ttl time.Duration, filter PayloadFilter, maxValueSize int) Main {
+	m.changes = &changes{
+		store:        store,
+		ttl:          ttl,
+		filter:       filter,
+		maxValueSize: maxValueSize,
+	}
+
+	return m
+}
+
+func (m Main) emitClusterChange(ctx context.Context, clusterID string, state entity.ProjectedClusterState) error {
+	if m.changes == nil {
+		return nil
+	}
+
+	payload := state.Payload
+	if m.changes.filter != nil {
+		payload = m.changes.filter(payload)
+	}
+
+	// Same representation as the stored state
+	current := previousClusterState{ID: state.ID, Timestamp: state.Timestamp}
+
+	data, err := json.Marshal(previousClusterState{ID: state.ID, Timestamp: state.Timestamp, Payload: payload})
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrStateStore, nil, "failed to marshal previous cluster state")
+	}
+
+	err = unmarshalJSON(data, &current, m.useNumber)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrStateStore, nil, "failed to unmarshal previous cluster state")
+	}
+
+	previous, raw, found, err := m.getPreviousClusterState(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		// Duplicated or out of order cluster state: the previous one is kept
+		if previous.ID == current.ID || current.Timestamp.Before(previous.Timestamp) {
+			return nil
+		}
+
+		err := m.writeClusterChange(ctx, clusterID, previous, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	value, err := m.encodePreviousClusterState(ctx, clusterID, current, data)
+	if err != nil {
+		return err
+	}
+
+	// Conditional write: a cluster state processed concurrently (e.g. by a backfill) is compared again on retry
+	set, err := m.changes.store.CompareAndSetState(ctx, stateKindPreviousClusterState, clusterID, raw, value, m.changes.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to store previous cluster state: %w", err)
+	}
+
+	if !set {
+		return common.NewRetryableErrProcessingError(errChangesConflict, categoryErrStateStore, nil, "failed to store previous cluster state %s", clusterID)
+	}
+
+	return nil
+}
+
+// encodePreviousClusterState compresses the json representation of the state, without its payload if too large
+func (m Main) encodePreviousClusterState(ctx context.Context, clusterID string, state previousClusterState, data []byte) ([]byte, error) {
+	ret := common.Compress(data, []byte{encodingVersionZstdJSON})
+
+	if m.changes.maxValueSize <= 0 || len(ret) <= m.changes.maxValueSize {
+		return ret, nil
+	}
+
+	log.FromContext(ctx).Info("Cluster state too large to be compared, next change not emitted", "cluster_id", clusterID, "size", len(ret), "limit", m.changes.maxValueSize)
+
+	data, err := json.Marshal(previousClusterState{ID: state.ID, Timestamp: state.Timestamp})
+	if err != nil {
+		return nil, common.NewErrProcessingError(err, categoryErrStateStore, nil, "failed to marshal previous cluster state")
+	}
+
+	return common.Compress(data, []byte{encodingVersionZstdJSON}), nil
+}
+
+func (m Main) writeClusterChange(ctx context.Context, clusterID string, previous, current previousClusterState) error {
+	// Previous cluster state too large to be stored
+	if previous.Payload == nil {
+		return nil
+	}
+
+	diff := make([]interface{}, 0)
+	diffMaps("", previous.Payload, current.Payload, changesIgnoredFields, &diff)
+
+	if len(diff) == 0 {
+		return nil
+	}
+
+	changeID, err := m.hashPayload(map[string]interface{}{
+		"previous_cluster_state_id": previous.ID,
+		"cluster_state_id":          current.ID,
+	})
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, nil, "failed to compute cluster change id")
+	}
+
+	change := entity.ClusterChange{
+		ID:        changeID,
+		Timestamp: current.Timestamp,
+		Payload: map[string]interface{}{
+			"cluster_change_id":         changeID,
+			"cluster_id":                clusterID,
+			"previous_cluster_state_id": previous.ID,
+			"cluster_state_id":          current.ID,
+			"previous_updated_at":       FormatDate(previous.Timestamp),
+			"updated_at":                FormatDate(current.Timestamp),
+			"changes":                   diff,
+		},
+		Stream: StreamChanges,
+	}
+
+	err = m.projectionWriter.WriteClusterChange(ctx, change)
+	if err != nil {
+		return fmt.Errorf("failed to write cluster change: %w", err)
+	}
+
+	return nil
+}
+
+// getPreviousClusterState returns the stored state and its raw value (nil when not stored), found is false when invalid
+func (m Main) getPreviousClusterState(ctx context.Context, clusterID string) (previousClusterState, []byte, bool, error) {
+	ret := previousClusterState{}
+
+	value, found, err := m.changes.store.GetState(ctx, stateKindPreviousClusterState, clusterID)
+	if err != nil {
+		return ret, nil, false, fmt.Errorf("failed to get previous cluster state: %w", err)
+	}
+
+	if !found {
+		return ret, nil, false, nil
+	}
+
+	err = decodePreviousClusterState(value, &ret, m.useNumber)
+	if err != nil {
+		// The next change is emitted from this cluster state
+		log.FromContext(ctx).Error(err, "Invalid previous cluster state, ignored", "cluster_id", clusterID)
+
+		return ret, value, false, nil
+	}
+
+	return ret, value, true, nil
+}
+
+func decodePreviousClusterState(value []byte, state *previousClusterState, useNumber bool) error {
+	if len(value) == 0 {
+		return errUnknownEncoding
+	}
+
+	data := value
+
+	switch value[0] {
+	case '{':
+		// Legacy encoding: plain json
+	case encodingVersionZstdJSON:
+		decompressed, err := common.Decompress(value[1:])
+		if err != nil {
+			return fmt.Errorf("failed to decompress previous cluster state: %w", err)
+		}
+
+		data = decompressed
+	default:
+		return fmt.Errorf("%w: %x", errUnknownEncoding, value[0])
+	}
+
+	return unmarshalJSON(data, state, useNumber)
+}
+
+// diffMaps appends a {path, from, to} change per modified leaf. Arrays of objects having an id are compared by id
+// (e.g. hosts[<host id>].status), other arrays are compared as a whole.
+func diffMaps(prefix string, from, to map[string]interface{}, ignored map[string]struct{}, diff *[]interface{}) {
+	keys := make([]string, 0, len(from)+len(to))
+
+	for k := range from {
+		keys = append(keys, k)
+	}
+
+	for k := range to {
+		if _, found := from[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, found := ignored[k]; found {
+			continue
+		}
+
+		diffValues(prefix+k, from[k], to[k], diff)
+	}
+}
+
+func diffValues(path string, from, to interface{}, diff *[]interface{}) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+
+	if fromIsMap && toIsMap {
+		diffMaps(path+".", fromMap, toMap, nil, diff)
+
+		return
+	}
+
+	fromByID, fromHasIDs := indexByID(from)
+	toByID, toHasIDs := indexByID(to)
+
+	if fromHasIDs && toHasIDs {
+		diffMaps(path+"[", fromByID, toByID, nil, diff)
+
+		return
+	}
+
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+
+	*diff = append(*diff, map[string]interface{}{
+		"path": path,
+		"from": from,
+		"to":   to,
+	})
+}
+
+// indexByID indexes an array of objects by id, keys are suffixed by ']' to build the path
+func indexByID(value interface{}) (map[string]interface{}, bool) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	ret := make(map[string]interface{}, len(items))
+
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		id, ok := obj["id"].(string)
+		if !ok || id == "" {
+			return nil, false
+		}
+
+		ret[id+"]"] = obj
+	}
+
+	return ret, true
+}
@@ -0,0 +1,174 @@
+package processing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func TestClusterChanges(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter).WithClusterChanges(memoryStateStore{}, 0, nil, 0)
+
+	ctx := context.Background()
+
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// First cluster state: nothing to compare with
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return([]entity.HostState{
+		{ClusterID: "cluster-1", HostID: "host-1", Payload: map[string]interface{}{"id": "host-1", "status": "known"}},
+	}, nil)
+
+	first := clusterStateEvent("installing", "2025-02-03T20:40:00.000Z")
+
+	err := main.Process(ctx, first)
+	require.NoError(t, err, "failed to process cluster state")
+
+	firstID, err := processing.HashPayload(first.Payload)
+	require.NoError(t, err, "failed to hash payload")
+
+	// Second cluster state: status of the cluster and of its host changed
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return([]entity.HostState{
+		{ClusterID: "cluster-1", HostID: "host-1", Payload: map[string]interface{}{"id": "host-1", "status": "installed"}},
+	}, nil)
+
+	second := clusterStateEvent("installed", "2025-02-03T21:00:00.000Z")
+
+	secondID, err := processing.HashPayload(second.Payload)
+	require.NoError(t, err, "failed to hash payload")
+
+	projectionWriter.EXPECT().WriteClusterChange(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, change entity.ClusterChange) error {
+			assert.Equal(t, map[string]interface{}{
+				"cluster_change_id":         change.ID,
+				"cluster_id":                "cluster-1",
+				"previous_cluster_state_id": firstID,
+				"cluster_state_id":          secondID,
+				"previous_updated_at":       "2025-02-03T20:40:00.000000Z",
+				"updated_at":                "2025-02-03T21:00:00.000000Z",
+				"changes": []interface{}{
+					map[string]interface{}{"path": "hosts[host-1].status", "from": "known", "to": "installed"},
+					map[string]interface{}{"path": "status", "from": "installing", "to": "installed"},
+				},
+			}, change.Payload)
+
+			return nil
+		})
+
+	err = main.Process(ctx, second)
+	require.NoError(t, err, "failed to process cluster state")
+
+	// Out of order cluster state: no change emitted
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil)
+
+	err = main.Process(ctx, clusterStateEvent("preparing-for-installation", "2025-02-03T20:30:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+}
+
+func TestClusterChangesFilterAndLimit(t *testing.T) {
+	t.Parallel()
+
+	// Only the status is exported
+	filter := func(payload map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"status": payload["status"]}
+	}
+
+	type testCase struct {
+		name         string
+		maxValueSize int
+		changes      []interface{} // nil when no change is emitted
+	}
+
+	testCases := []testCase{
+		{
+			name: "filtered",
+			changes: []interface{}{
+				map[string]interface{}{"path": "status", "from": "installing", "to": "installed"},
+			},
+		},
+		{
+			name:         "previous cluster state too large",
+			maxValueSize: 16,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			hostRepo := mock.NewMockHostState(ctrl)
+			projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+			main := processing.NewMain(hostRepo, projectionWriter).WithClusterChanges(memoryStateStore{}, 0, filter, tc.maxValueSize)
+
+			ctx := context.Background()
+
+			projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil).Times(2)
+
+			err := main.Process(ctx, clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+			require.NoError(t, err, "failed to process cluster state")
+
+			// openshift_version isn't exported: no change for it
+			second := clusterStateEvent("installed", "2025-02-03T21:00:00.000Z")
+			second.Payload["openshift_version"] = "4.19.0"
+
+			if tc.changes != nil {
+				projectionWriter.EXPECT().WriteClusterChange(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, change entity.ClusterChange) error {
+						assert.Equal(t, tc.changes, change.Payload["changes"])
+
+						return nil
+					})
+			}
+
+			err = main.Process(ctx, second)
+			require.NoError(t, err, "failed to process cluster state")
+		})
+	}
+}
+
+func TestClusterChangesConflict(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	memory := memoryStateStore{}
+	conflicts := 1
+
+	main := processing.NewMain(hostRepo, projectionWriter).WithClusterChanges(conflictingStateStore{memoryStateStore: memory, conflicts: &conflicts}, 0, nil, 0)
+
+	ctx := context.Background()
+
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil).Times(2)
+
+	// The previous cluster state was updated concurrently: retried
+	err := main.Process(ctx, clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+	assert.ErrorIs(t, err, pipeline.ErrRetryableError, "conflicts should be retried")
+
+	_, stored := memory["previous_cluster_state:cluster-1"]
+	assert.False(t, stored, "previous cluster state should not be stored")
+
+	err = main.Process(ctx, clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	_, stored = memory["previous_cluster_state:cluster-1"]
+	assert.True(t, stored, "previous cluster state should be stored")
+}
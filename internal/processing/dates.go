@@ -0,0 +1,159 @@
+package processing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+const (
+	// DateFormatLegacy is the only format accepted historically, e.g. 2024-11-21T02:57:38.485Z
+	DateFormatLegacy = "legacy"
+	// DateFormatRFC3339 accepts offsets (+02:00) & up to nanosecond precision
+	DateFormatRFC3339 = "rfc3339"
+	// DateFormatEpochMillis accepts milliseconds since epoch, as a string or a number
+	DateFormatEpochMillis = "epoch_millis"
+
+	legacyLayout = "2006-01-02T15:04:05.9Z"
+)
+
+var errNoMatchingFormat = errors.New("no matching date format")
+
+// DateParser parses the input dates with the first matching format. Dates are returned in UTC.
+// The zero value only accepts the legacy format.
+type DateParser struct {
+	formats []string
+	counter *prometheus.CounterVec
+}
+
+// NewDateParser accepts DateFormatLegacy, DateFormatRFC3339, DateFormatEpochMillis or any go time layout
+func NewDateParser(formats []string) (DateParser, error) {
+	if len(formats) == 0 {
+		return DateParser{}, errors.New("at least one date format is expected")
+	}
+
+	for i, f := range formats {
+		if f == "" {
+			return DateParser{}, fmt.Errorf("empty date format at index %d", i)
+		}
+	}
+
+	return DateParser{formats: formats}, nil
+}
+
+// WithMetrics counts the parsed dates by matching format
+func (p DateParser) WithMetrics(registry prometheus.Registerer, config pipeline.MetricsConfig) (DateParser, error) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Name:      "date_formats_total",
+		Help:      "Parsed dates by matching format.",
+	}, []string{"format"})
+
+	err := registry.Register(counter)
+	if err != nil {
+		return p, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	p.counter = counter
+
+	return p, nil
+}
+
+// WithoutMetrics doesn't count the parsed dates, e.g. for the secondary dates of an event
+func (p DateParser) WithoutMetrics() DateParser {
+	p.counter = nil
+
+	return p
+}
+
+func (p DateParser) Parse(date string) (time.Time, error) {
+	formats := p.formats
+	if len(formats) == 0 {
+		formats = []string{DateFormatLegacy}
+	}
+
+	for _, f := range formats {
+		ret, err := parseDate(f, date)
+		if err != nil {
+			continue
+		}
+
+		if p.counter != nil {
+			p.counter.WithLabelValues(f).Inc()
+		}
+
+		return ret.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("failed to parse time %s: %w", date, errNoMatchingFormat)
+}
+
+// Extract parses the date of key. It also returns the raw date as a string, numbers being formatted as integers.
+func (p DateParser) Extract(payload map[string]interface{}, key string) (string, time.Time, error) {
+	var raw string
+
+	switch v := payload[key].(type) {
+	case nil:
+		if _, present := payload[key]; !present {
+			return "", time.Time{}, errMissingKey
+		}
+
+		return "", time.Time{}, errFieldInvalidType
+	case string:
+		if v == "" {
+			return "", time.Time{}, errEmptyValue
+		}
+
+		raw = v
+	case float64:
+		raw = strconv.FormatInt(int64(v), 10)
+	case json.Number:
+		raw = v.String()
+	default:
+		return "", time.Time{}, errFieldInvalidType
+	}
+
+	ret, err := p.Parse(raw)
+
+	return raw, ret, err
+}
+
+// ExtractEventTime parses the time field of the event type
+func (p DateParser) ExtractEventTime(event entity.Event) (time.Time, error) {
+	eventType, found := LookupEventType(event.Name)
+	if !found || eventType.TimeField == "" {
+		return time.Time{}, fmt.Errorf("unexpected event name: %s", event.Name)
+	}
+
+	_, ret, err := p.Extract(event.Payload, eventType.TimeField)
+	if err != nil {
+		return ret, fmt.Errorf("failed to extract date time: %w", err)
+	}
+
+	return ret, nil
+}
+
+func parseDate(format string, date string) (time.Time, error) {
+	switch format {
+	case DateFormatLegacy:
+		return time.Parse(legacyLayout, date)
+	case DateFormatRFC3339:
+		return time.Parse(time.RFC3339Nano, date)
+	case DateFormatEpochMillis:
+		millis, err := strconv.ParseInt(date, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.UnixMilli(millis), nil
+	default:
+		return time.Parse(format, date)
+	}
+}
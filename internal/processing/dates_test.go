@@ -0,0 +1,155 @@
+package processing_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func TestDateParser(t *testing.T) {
+	t.Parallel()
+
+	parser, err := processing.NewDateParser([]string{
+		processing.DateFormatLegacy,
+		processing.DateFormatRFC3339,
+		processing.DateFormatEpochMillis,
+	})
+	require.NoError(t, err, "failed to create date parser")
+
+	type testCase struct {
+		name     string
+		date     string
+		valid    bool
+		expected time.Time
+	}
+
+	cases := []testCase{
+		{
+			name:     "legacy",
+			date:     "2024-11-21T02:57:38.485Z",
+			valid:    true,
+			expected: time.Date(2024, 11, 21, 2, 57, 38, 485000000, time.UTC),
+		},
+		{
+			name:     "offset",
+			date:     "2024-11-21T04:57:38.485+02:00",
+			valid:    true,
+			expected: time.Date(2024, 11, 21, 2, 57, 38, 485000000, time.UTC),
+		},
+		{
+			name:     "nanoseconds",
+			date:     "2024-11-21T02:57:38.123456789Z",
+			valid:    true,
+			expected: time.Date(2024, 11, 21, 2, 57, 38, 123456789, time.UTC),
+		},
+		{
+			name:     "epoch millis",
+			date:     "1732157858485",
+			valid:    true,
+			expected: time.Date(2024, 11, 21, 2, 57, 38, 485000000, time.UTC),
+		},
+		{
+			name: "unknown format",
+			date: "02 Jan 06 15:04 MST",
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts, err := parser.Parse(c.date)
+			assert.Equal(t, c.valid, err == nil, err)
+
+			if c.valid {
+				assert.Equal(t, c.expected, ts)
+				assert.Equal(t, time.UTC, ts.Location())
+			}
+		})
+	}
+}
+
+func TestDateParserStrict(t *testing.T) {
+	t.Parallel()
+
+	// The zero value only accepts the legacy format
+	parser := processing.DateParser{}
+
+	_, err := parser.Parse("2024-11-21T02:57:38.485Z")
+	require.NoError(t, err, "legacy format should be accepted")
+
+	_, err = parser.Parse("2024-11-21T04:57:38.485+02:00")
+	assert.Error(t, err, "offsets should be rejected")
+
+	_, err = parser.Parse("1732157858485")
+	assert.Error(t, err, "epoch millis should be rejected")
+
+	_, err = processing.NewDateParser(nil)
+	assert.Error(t, err, "formats are mandatory")
+}
+
+func TestDateParserExtract(t *testing.T) {
+	t.Parallel()
+
+	parser, err := processing.NewDateParser([]string{processing.DateFormatEpochMillis})
+	require.NoError(t, err, "failed to create date parser")
+
+	expected := time.Date(2024, 11, 21, 2, 57, 38, 485000000, time.UTC)
+
+	raw, ts, err := parser.Extract(map[string]interface{}{"updated_at": float64(1732157858485)}, "updated_at")
+	require.NoError(t, err, "float64 epoch millis should be accepted")
+	assert.Equal(t, "1732157858485", raw)
+	assert.Equal(t, expected, ts)
+
+	_, ts, err = parser.Extract(map[string]interface{}{"updated_at": json.Number("1732157858485")}, "updated_at")
+	require.NoError(t, err, "json.Number epoch millis should be accepted")
+	assert.Equal(t, expected, ts)
+
+	_, _, err = parser.Extract(map[string]interface{}{}, "updated_at")
+	assert.Error(t, err, "missing date")
+
+	_, _, err = parser.Extract(map[string]interface{}{"updated_at": true}, "updated_at")
+	assert.Error(t, err, "invalid type")
+}
+
+func TestDateParserMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+
+	parser, err := processing.NewDateParser([]string{processing.DateFormatLegacy, processing.DateFormatRFC3339})
+	require.NoError(t, err, "failed to create date parser")
+
+	parser, err = parser.WithMetrics(registry, pipeline.MetricsConfig{Namespace: "test"})
+	require.NoError(t, err, "failed to register metrics")
+
+	_, err = parser.Parse("2024-11-21T02:57:38.485Z")
+	require.NoError(t, err)
+
+	_, err = parser.Parse("2024-11-21T04:57:38+02:00")
+	require.NoError(t, err)
+
+	_, err = parser.Parse("2024-11-21T04:57:38.1+02:00")
+	require.NoError(t, err)
+
+	expected := `
+# HELP test_date_formats_total Parsed dates by matching format.
+# TYPE test_date_formats_total counter
+test_date_formats_total{format="legacy"} 1
+test_date_formats_total{format="rfc3339"} 2
+`
+
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+	assert.NoError(t, err, "unexpected metrics")
+}
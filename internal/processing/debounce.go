@@ -0,0 +1,111 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+)
+
+const (
+	stateKindLastClusterState = "last_cluster_state"
+
+	categoryErrStateStore = "state_store"
+)
+
+// debounce re-emits the last cluster state when one of its hosts is updated shortly after it
+type debounce struct {
+	store  repo.StateStore
+	window time.Duration
+	clock  clockwork.Clock
+}
+
+// lastClusterState holds the scrubbed & anonymized inputs of the projection, never the raw event
+type lastClusterState struct {
+	Inputs      clusterStateInputs `json:"inputs"`
+	ProcessedAt time.Time          `json:"processed_at"`
+}
+
+// WithClusterStateDebounce re-emits a cluster state when a host state of the cluster is processed less than window after it.
+// The cluster_state_id only depends on the cluster payload: the re-emitted projection replaces the stale one.
+func (m Main) WithClusterStateDebounce(store repo.StateStore, window time.Duration, clock clockwork.Clock) Main {
+	m.debounce = &debounce{
+		store:  store,
+		window: window,
+		clock:  clock,
+	}
+
+	return m
+}
+
+// rememberClusterState keeps the last cluster state during the debounce window
+func (m Main) rememberClusterState(ctx context.Context, inputs clusterStateInputs) error {
+	if m.debounce == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(lastClusterState{
+		Inputs:      inputs,
+		ProcessedAt: m.debounce.clock.Now(),
+	})
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrStateStore, nil, "failed to marshal last cluster state")
+	}
+
+	err = m.debounce.store.SetState(ctx, stateKindLastClusterState, inputs.ClusterID, value, m.debounce.window)
+	if err != nil {
+		return fmt.Errorf("failed to store last cluster state: %w", err)
+	}
+
+	return nil
+}
+
+// reemitClusterState projects the last cluster state again, with the updated hosts, if it was processed during the window
+func (m Main) reemitClusterState(ctx context.Context, clusterID string) error {
+	if m.debounce == nil {
+		return nil
+	}
+
+	value, found, err := m.debounce.store.GetState(ctx, stateKindLastClusterState, clusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get last cluster state: %w", err)
+	}
+
+	if !found {
+		return nil
+	}
+
+	last := lastClusterState{}
+
+	err = unmarshalJSON(value, &last, m.useNumber)
+	if err == nil && last.Inputs.ClusterID == "" {
+		err = errors.New("missing inputs, stored by a previous version") // raw events were stored
+	}
+
+	if err != nil {
+		// Not worth failing the host state: the cluster state has already been projected once
+		log.FromContext(ctx).Error(err, "Invalid last cluster state, not re-emitted", "cluster_id", clusterID)
+
+		return nil
+	}
+
+	// The store expiration is not precise enough
+	if m.debounce.clock.Since(last.ProcessedAt) > m.debounce.window {
+		return nil
+	}
+
+	// Window isn't extended: the last cluster state isn't stored again
+	err = m.reprojectClusterState(ctx, last.Inputs)
+	if err != nil {
+		return fmt.Errorf("failed to re-emit cluster state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package processing_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+// memoryStateStore ignores the ttl, the debounce checks the window itself
+type memoryStateStore map[string][]byte
+
+func (m memoryStateStore) GetState(_ context.Context, kind, clusterID string) ([]byte, bool, error) {
+	ret, found := m[kind+":"+clusterID]
+
+	return ret, found, nil
+}
+
+func (m memoryStateStore) SetState(_ context.Context, kind, clusterID string, value []byte, _ time.Duration) error {
+	m[kind+":"+clusterID] = value
+
+	return nil
+}
+
+func (m memoryStateStore) CompareAndSetState(_ context.Context, kind, clusterID string, previous, value []byte, _ time.Duration) (bool, error) {
+	current, found := m[kind+":"+clusterID]
+	if found != (previous != nil) || !bytes.Equal(current, previous) {
+		return false, nil
+	}
+
+	m[kind+":"+clusterID] = value
+
+	return true, nil
+}
+
+func TestClusterStateDebounce(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+	clock := clockwork.NewFakeClock()
+
+	store := memoryStateStore{}
+	main := processing.NewMain(hostRepo, projectionWriter).WithClusterStateDebounce(store, time.Minute, clock)
+
+	ctx := context.Background()
+
+	clusterState := entity.Event{
+		Name: "ClusterState",
+		Payload: map[string]interface{}{
+			"id":           "cluster-1",
+			"created_at":   "2025-02-03T20:00:00.000Z",
+			"updated_at":   "2025-02-03T21:00:00.000Z",
+			"email_domain": "example.com",
+			"user_name":    "john",
+		},
+	}
+
+	hostState := func(hostID string) entity.Event {
+		return entity.Event{
+			Name:    "HostState",
+			Payload: map[string]interface{}{"cluster_id": "cluster-1", "id": hostID},
+		}
+	}
+
+	hostCount := func(count int) gomock.Matcher {
+		return gomock.Cond(func(x any) bool {
+			state, ok := x.(entity.ProjectedClusterState)
+
+			return ok && len(state.Payload["hosts"].([]interface{})) == count
+		})
+	}
+
+	// Cluster state processed without hosts
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil)
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), hostCount(0)).Return(nil)
+
+	err := main.Process(ctx, clusterState)
+	require.NoError(t, err, "failed to process cluster state")
+
+	// Only anonymized data is stored
+	stored := string(store["last_cluster_state:cluster-1"])
+	assert.NotContains(t, stored, "john")
+	assert.Contains(t, stored, "527bd5b5d689e2c32ae974c6229ff785")
+
+	// Late host state: cluster state re-emitted with the host
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).Return(nil)
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return([]entity.HostState{
+		{ClusterID: "cluster-1", HostID: "host-1", Payload: map[string]interface{}{"id": "host-1"}},
+	}, nil)
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), hostCount(1)).Return(nil)
+
+	clock.Advance(30 * time.Second)
+
+	err = main.Process(ctx, hostState("host-1"))
+	require.NoError(t, err, "failed to process host state")
+
+	// Re-emission doesn't extend the window: no more cluster state
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).Return(nil)
+
+	clock.Advance(31 * time.Second)
+
+	err = main.Process(ctx, hostState("host-2"))
+	require.NoError(t, err, "failed to process host state")
+}
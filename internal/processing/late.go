@@ -9,17 +9,20 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
 	"github.com/openshift-assisted/ccx-exporter/internal/log"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
 type CountLateData struct {
-	counter *prometheus.CounterVec
-	clock   clockwork.Clock
-	inner   pipeline.Processing[entity.Event]
+	counter    *prometheus.CounterVec
+	clock      clockwork.Clock
+	schedule   late.Schedule
+	dateParser DateParser
+	inner      pipeline.Processing[entity.Event]
 }
 
-func NewCountLateData(p pipeline.Processing[entity.Event], registry prometheus.Registerer, clock clockwork.Clock, config pipeline.MetricsConfig) (pipeline.Processing[entity.Event], error) {
+func NewCountLateData(p pipeline.Processing[entity.Event], schedule late.Schedule, dateParser DateParser, registry prometheus.Registerer, clock clockwork.Clock, config pipeline.MetricsConfig) (pipeline.Processing[entity.Event], error) {
 	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: config.Namespace,
 		Name:      "late_data_total",
@@ -32,9 +35,11 @@ func NewCountLateData(p pipeline.Processing[entity.Event], registry prometheus.R
 	}
 
 	ret := CountLateData{
-		counter: counter,
-		clock:   clock,
-		inner:   p,
+		counter:    counter,
+		clock:      clock,
+		schedule:   schedule,
+		dateParser: dateParser,
+		inner:      p,
 	}
 
 	return ret, nil
@@ -46,19 +51,20 @@ func (p CountLateData) Process(ctx context.Context, event entity.Event) error {
 		return err // Count only successfully processed data
 	}
 
-	if event.Name == eventNameHostState {
+	eventType, found := LookupEventType(event.Name)
+	if !found || eventType.LatePolicy == LatePolicyIgnore {
 		return nil
 	}
 
-	eventTime, err := ExtractEventTime(event)
+	eventTime, err := p.dateParser.ExtractEventTime(event)
 	if err != nil {
-		log.Logger().Error(err, "Failed to extract time to count late data")
+		log.FromContext(ctx).Error(err, "Failed to extract time to count late data")
 
 		return nil // Not a processing error
 	}
 
-	limit := p.computeDeadline()
-	if eventTime.After(limit) {
+	// Same boundary as the late routing of the projections: the deadline itself is not late
+	if !p.schedule.IsLate(eventTime, p.clock.Now()) {
 		return nil
 	}
 
@@ -68,20 +74,11 @@ func (p CountLateData) Process(ctx context.Context, event entity.Event) error {
 	return nil
 }
 
-// CCX processes data of the previous day twice per day. Last time at 2PM.
-// Therefore before 2PM, data from previous day are not late yet.
-// But after 2PM, only data of the current day will be processed.
+// CCX processes data of the previous day until its closing time, 2PM by default.
+// Therefore before it, data from previous day are not late yet.
+// But after it, only data of the current day will be processed.
 func (p CountLateData) computeDeadline() time.Time {
-	now := p.clock.Now().UTC()
-	lastCCXProcessingTime := time.Date(now.Year(), now.Month(), now.Day(), 14, 0, 0, 0, time.UTC)
-
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-
-	if now.After(lastCCXProcessingTime) {
-		return today
-	}
-
-	return today.Add(-24 * time.Hour)
+	return p.schedule.Deadline(p.clock.Now())
 }
 
 func (p CountLateData) computeEventDayLabel(eventTime time.Time) string {
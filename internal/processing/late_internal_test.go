@@ -1,11 +1,20 @@
 package processing
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/late"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
 func TestComputeDeadline(t *testing.T) {
@@ -57,3 +66,52 @@ func TestComputeDeadline(t *testing.T) {
 		})
 	}
 }
+
+func TestCountLateDataDeadline(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name      string
+		eventTime string
+		late      bool
+	}
+
+	// Deadline is 2024-12-25T00:00:00Z
+	cases := []testCase{
+		{name: "Before the deadline", eventTime: "2024-12-24T23:59:59.999Z", late: true},
+		{name: "At the deadline", eventTime: "2024-12-25T00:00:00.000Z", late: false},
+		{name: "After the deadline", eventTime: "2024-12-25T00:00:00.001Z", late: false},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			registry := prometheus.NewRegistry()
+			clock := clockwork.NewFakeClockAt(time.Date(2024, 12, 25, 15, 0, 0, 0, time.UTC))
+
+			p, err := NewCountLateData(IgnoreEvent{}, late.Schedule{}, DateParser{}, registry, clock, pipeline.MetricsConfig{Namespace: "test"})
+			require.NoError(t, err, "failed to create late data counter")
+
+			err = p.Process(context.Background(), entity.Event{
+				Name:    eventNameEvent,
+				Payload: map[string]interface{}{"event_time": c.eventTime},
+			})
+			require.NoError(t, err, "failed to process event")
+
+			expected := ""
+			if c.late {
+				expected = `
+# HELP test_late_data_total Late data counter by event name and day.
+# TYPE test_late_data_total counter
+test_late_data_total{event_day="2024-12-24",name="Event"} 1
+`
+			}
+
+			err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+			assert.NoError(t, err, "unexpected metrics")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+const (
+	latencySourceEventTime      = "event_time"
+	latencySourceKafkaTimestamp = "kafka_timestamp"
+)
+
+// EndToEndLatency measures the time between the event and the end of its processing.
+// The event time is used when the event type has one, the kafka timestamp otherwise.
+type EndToEndLatency struct {
+	histogram  *prometheus.HistogramVec
+	clock      clockwork.Clock
+	dateParser DateParser
+	inner      pipeline.Processing[entity.Event]
+}
+
+func NewEndToEndLatency(p pipeline.Processing[entity.Event], dateParser DateParser, registry prometheus.Registerer, clock clockwork.Clock, config pipeline.MetricsConfig) (pipeline.Processing[entity.Event], error) {
+	buckets := config.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.ExponentialBuckets(1, 4, 10) // 1s -> ~3 days
+	}
+
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "end_to_end_latency_seconds",
+		Help:      "Time between the event (event time or kafka timestamp) and the end of its processing, by event name and time source.",
+		Buckets:   buckets,
+	}, []string{"name", "source"})
+
+	err := registry.Register(histogram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	ret := EndToEndLatency{
+		histogram:  histogram,
+		clock:      clock,
+		dateParser: dateParser,
+		inner:      p,
+	}
+
+	return ret, nil
+}
+
+func (p EndToEndLatency) Process(ctx context.Context, event entity.Event) error {
+	err := p.inner.Process(ctx, event)
+	if err != nil {
+		return err // Measure only successfully processed data
+	}
+
+	source := latencySourceEventTime
+
+	eventTime, err := p.dateParser.ExtractEventTime(event)
+	if err != nil {
+		msg, found := pipeline.MessageFromContext(ctx)
+		if !found || msg.Timestamp.IsZero() {
+			return nil
+		}
+
+		source = latencySourceKafkaTimestamp
+		eventTime = msg.Timestamp
+	}
+
+	p.histogram.WithLabelValues(EventNameLabel(event), source).Observe(p.clock.Since(eventTime).Seconds())
+
+	return nil
+}
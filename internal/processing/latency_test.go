@@ -0,0 +1,104 @@
+package processing_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline/mock"
+)
+
+func TestEndToEndLatency(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		name        string
+		event       entity.Event
+		msg         *sarama.ConsumerMessage
+		err         error
+		expectation string
+	}
+
+	cases := []testCase{
+		{
+			name:  "event time",
+			event: entity.Event{Name: "Event", Payload: map[string]interface{}{"event_time": "2025-01-02T11:59:00Z"}},
+			msg:   &sarama.ConsumerMessage{Timestamp: now.Add(-time.Second)},
+			expectation: `
+				processing_end_to_end_latency_seconds_bucket{name="Event",source="event_time",le="30"} 0
+				processing_end_to_end_latency_seconds_bucket{name="Event",source="event_time",le="60"} 1
+				processing_end_to_end_latency_seconds_bucket{name="Event",source="event_time",le="+Inf"} 1
+				processing_end_to_end_latency_seconds_sum{name="Event",source="event_time"} 60
+				processing_end_to_end_latency_seconds_count{name="Event",source="event_time"} 1
+			`,
+		},
+		{
+			name:  "kafka timestamp without event time",
+			event: entity.Event{Name: "HostState", Payload: map[string]interface{}{}},
+			msg:   &sarama.ConsumerMessage{Timestamp: now.Add(-20 * time.Second)},
+			expectation: `
+				processing_end_to_end_latency_seconds_bucket{name="HostState",source="kafka_timestamp",le="30"} 1
+				processing_end_to_end_latency_seconds_bucket{name="HostState",source="kafka_timestamp",le="60"} 1
+				processing_end_to_end_latency_seconds_bucket{name="HostState",source="kafka_timestamp",le="+Inf"} 1
+				processing_end_to_end_latency_seconds_sum{name="HostState",source="kafka_timestamp"} 20
+				processing_end_to_end_latency_seconds_count{name="HostState",source="kafka_timestamp"} 1
+			`,
+		},
+		{
+			name:  "no time",
+			event: entity.Event{Name: "HostState", Payload: map[string]interface{}{}},
+		},
+		{
+			name:  "failed processing",
+			event: entity.Event{Name: "Event", Payload: map[string]interface{}{"event_time": "2025-01-02T11:59:00Z"}},
+			err:   errors.New("failed"),
+		},
+	}
+
+	for i := range cases {
+		c := cases[i]
+
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			inner := mock.NewMockProcessing[entity.Event](ctrl)
+			inner.EXPECT().Process(gomock.Any(), c.event).Return(c.err)
+
+			dateParser, err := processing.NewDateParser([]string{processing.DateFormatRFC3339})
+			require.NoError(t, err)
+
+			registry := prometheus.NewPedanticRegistry()
+
+			p, err := processing.NewEndToEndLatency(inner, dateParser, registry, clockwork.NewFakeClockAt(now), pipeline.MetricsConfig{
+				Namespace: "processing",
+				Buckets:   []float64{30, 60},
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			if c.msg != nil {
+				ctx = pipeline.ContextWithMessage(ctx, c.msg)
+			}
+
+			assert.Equal(t, c.err, p.Process(ctx, c.event))
+			assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+				# HELP processing_end_to_end_latency_seconds Time between the event (event time or kafka timestamp) and the end of its processing, by event name and time source.
+				# TYPE processing_end_to_end_latency_seconds histogram
+			`+c.expectation)))
+		})
+	}
+}
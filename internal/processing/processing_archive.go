@@ -0,0 +1,30 @@
+package processing
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+// Archive stores the consumed records, so that projections can be derived again once kafka retention has passed
+type Archive struct {
+	rawEventRepo repo.RawEventWriter
+}
+
+func NewArchive(rawEventRepo repo.RawEventWriter) Archive {
+	return Archive{
+		rawEventRepo: rawEventRepo,
+	}
+}
+
+func (a Archive) Process(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	err := a.rawEventRepo.WriteRawEvent(ctx, msg)
+	if err != nil {
+		return pipeline.NewErrProcessingError(err, pipeline.ArchiveErrorCategory, nil)
+	}
+
+	return nil
+}
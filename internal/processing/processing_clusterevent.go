@@ -2,16 +2,25 @@ package processing
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/hex"
 	"fmt"
 
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
 )
 
 const categoryErrInvalidClusterEvent = "invalid_cluster_event"
 
+func init() {
+	RegisterEventType(EventType{
+		Name:       eventNameEvent,
+		Handler:    Main.processClusterEvent,
+		TimeField:  "event_time",
+		LatePolicy: LatePolicyCount,
+		Stream:     StreamEvents,
+	})
+}
+
 func (m Main) processClusterEvent(ctx context.Context, event entity.Event) error {
 	// Extract mandatory fields
 	clusterID, err := ExtractString(event.Payload, "cluster_id")
@@ -19,15 +28,10 @@ func (m Main) processClusterEvent(ctx context.Context, event entity.Event) error
 		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "failed to extract clusterID")
 	}
 
-	eventTime, err := ExtractString(event.Payload, "event_time")
-	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "failed to extract event_time")
-	}
-
-	// Validate date format
-	ts, err := ValidateDate(eventTime)
+	// Validate date format, the raw date is part of the event ID
+	eventTime, ts, err := m.dateParser.Extract(event.Payload, "event_time")
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "invalid date format %s", eventTime)
+		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "invalid event_time")
 	}
 
 	// Compute event ID
@@ -36,17 +40,29 @@ func (m Main) processClusterEvent(ctx context.Context, event entity.Event) error
 		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "failed to extract message")
 	}
 
-	eventID := m.computeEventID(clusterID, eventTime, message)
+	eventID, err := computeEventID(clusterID, eventTime, message)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "failed to compute event id")
+	}
 
 	// Create ClusterEvent entity
 	payload := CopyPayload(event.Payload)
 	payload["event_id"] = eventID
 	payload["event_time"] = FormatDate(ts)
 
+	// Scrub free text PII & anonymize, after computing the event ID which must stay stable
+	m.scrubber.Apply(event.Name, payload)
+
+	err = m.anonymizer.Apply(event.Name, ts, payload)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidClusterEvent, nil, "failed to anonymize payload")
+	}
+
 	clusterEvent := entity.ProjectedClusterEvent{
 		ID:        eventID,
 		Timestamp: ts,
 		Payload:   payload,
+		Stream:    streamOf(eventNameEvent),
 	}
 
 	// Call repo
@@ -55,12 +71,9 @@ func (m Main) processClusterEvent(ctx context.Context, event entity.Event) error
 		return fmt.Errorf("failed to write event: %w", err)
 	}
 
-	return nil
+	return m.updateSummaryFromEvent(ctx, clusterID, payload)
 }
 
-func (m Main) computeEventID(clusterID, eventTime, message string) string {
-	key := fmt.Sprintf("%s%s%s", eventTime, clusterID, message)
-	hash := md5.Sum([]byte(key))
-
-	return hex.EncodeToString(hash[:])
+func computeEventID(clusterID, eventTime, message string) (string, error) {
+	return anonymization.MD5([]byte(eventTime + clusterID + message))
 }
@@ -0,0 +1,57 @@
+package processing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+func TestProcessClusterEventAnonymization(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	anonymizer, err := anonymization.NewEngine([]anonymization.Rule{
+		{Event: "Event", Path: "user_name", Action: anonymization.ActionHash, To: "user_id"},
+		{Event: "Event", Path: "props", Action: anonymization.ActionDrop},
+	}, anonymization.HashFunc(anonymization.MD5))
+	require.NoError(t, err, "failed to create anonymizer")
+
+	main := processing.NewMain(hostRepo, projectionWriter).WithAnonymizer(anonymizer)
+
+	event := entity.Event{
+		Name: "Event",
+		Payload: map[string]interface{}{
+			"cluster_id": "cluster-1",
+			"event_time": "2025-02-03T21:02:45.465Z",
+			"message":    "Cluster installed",
+			"user_name":  "john",
+			"props":      "{}",
+		},
+	}
+
+	projectionWriter.EXPECT().WriteProjectedClusterEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, clusterEvent entity.ProjectedClusterEvent) error {
+			// Event ID computed before anonymization
+			assert.Equal(t, "ff9aa91ca5ee6da3413a75944445d5c5", clusterEvent.ID)
+			assert.Equal(t, clusterEvent.ID, clusterEvent.Payload["event_id"])
+			assert.Equal(t, "527bd5b5d689e2c32ae974c6229ff785", clusterEvent.Payload["user_id"])
+			assert.NotContains(t, clusterEvent.Payload, "user_name")
+			assert.NotContains(t, clusterEvent.Payload, "props")
+
+			return nil
+		})
+
+	err = main.Process(context.Background(), event)
+	require.NoError(t, err, "failed to process event")
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
@@ -18,89 +19,165 @@ const (
 	categoryErrHostWriterRepo      = "host_writer_repo"
 )
 
+func init() {
+	RegisterEventType(EventType{
+		Name:       eventNameClusterState,
+		Handler:    Main.processClusterState,
+		TimeField:  "updated_at",
+		LatePolicy: LatePolicyCount,
+		Stream:     StreamClusters,
+	})
+}
+
 func (m Main) processClusterState(ctx context.Context, event entity.Event) error {
-	payload := CopyPayload(event.Payload)
+	clusterState, inputs, err := m.projectClusterState(ctx, event)
+	if err != nil {
+		return err
+	}
 
+	err = m.rememberClusterState(ctx, inputs)
+	if err != nil {
+		return err
+	}
+
+	err = m.emitClusterChange(ctx, inputs.ClusterID, clusterState)
+	if err != nil {
+		return err
+	}
+
+	return m.updateSummaryFromClusterState(ctx, inputs.ClusterID, event, inputs.UpdatedAt)
+}
+
+// clusterStateInputs is a validated, scrubbed & anonymized cluster state, without its hosts
+type clusterStateInputs struct {
+	ID        string                 `json:"id"`
+	ClusterID string                 `json:"cluster_id"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	Payload   map[string]interface{} `json:"payload"`
+}
+
+func (m Main) projectClusterState(ctx context.Context, event entity.Event) (entity.ProjectedClusterState, clusterStateInputs, error) {
 	// Extract clusterID
 	clusterID, err := ExtractString(event.Payload, "id")
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, nil, "failed to extract id")
+		return entity.ProjectedClusterState{}, clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, nil, "failed to extract id")
+	}
+
+	hostStates, err := m.getSortedHostStates(ctx, clusterID)
+	if err != nil {
+		return entity.ProjectedClusterState{}, clusterStateInputs{}, err
+	}
+
+	inputs, err := m.makeClusterStateInputs(ctx, event, clusterID, hostStates)
+	if err != nil {
+		return entity.ProjectedClusterState{}, clusterStateInputs{}, err
 	}
 
-	// Get HostState for cluster
-	// Sorted by host id to have a deterministic cluster_state_id
+	clusterState, err := m.writeClusterState(ctx, inputs, hostStates)
+	if err != nil {
+		return entity.ProjectedClusterState{}, clusterStateInputs{}, err
+	}
+
+	return clusterState, inputs, nil
+}
+
+// reprojectClusterState writes a cluster state again, with the current hosts
+func (m Main) reprojectClusterState(ctx context.Context, inputs clusterStateInputs) error {
+	hostStates, err := m.getSortedHostStates(ctx, inputs.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.writeClusterState(ctx, inputs, hostStates)
+
+	return err
+}
+
+// getSortedHostStates returns the host states sorted by host id, to have a deterministic projection
+func (m Main) getSortedHostStates(ctx context.Context, clusterID string) ([]entity.HostState, error) {
 	hostStates, err := m.hostRepo.GetHostStates(ctx, clusterID)
 	if err != nil {
-		return fmt.Errorf("failed to get host states: %w", err)
+		return nil, fmt.Errorf("failed to get host states: %w", err)
 	}
 
 	sort.Slice(hostStates, func(i, j int) bool {
 		return hostStates[i].HostID < hostStates[j].HostID
 	})
 
-	hosts := make([]interface{}, 0, len(hostStates))
-	for _, hs := range hostStates {
-		hosts = append(hosts, hs.Payload)
-	}
+	return hostStates, nil
+}
 
-	payload["hosts"] = hosts
+// makeClusterStateInputs validates, scrubs & anonymizes the cluster fields. Hosts are anonymized by the HostState rules.
+func (m Main) makeClusterStateInputs(ctx context.Context, event entity.Event, clusterID string, hostStates []entity.HostState) (clusterStateInputs, error) {
+	payload := CopyPayload(event.Payload)
 
 	// Check Mandatory fields (created_at, updated_at, email_domain)
-	_, err = ExtractString(event.Payload, "created_at")
+	_, err := ExtractString(event.Payload, "created_at")
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "failed to extract created_at")
+		return clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(ctx, hostStates), "failed to extract created_at")
 	}
 
-	updatedAtStr, err := ExtractString(event.Payload, "updated_at")
+	_, updatedAt, err := m.dateParser.Extract(event.Payload, "updated_at")
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "failed to extract updated_at")
+		return clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(ctx, hostStates), "invalid updated_at")
 	}
 
 	_, err = ExtractString(event.Payload, "email_domain")
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "failed to extract email_domain")
+		return clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(ctx, hostStates), "failed to extract email_domain")
 	}
 
-	// Validate date
-	updatedAt, err := ValidateDate(updatedAtStr)
+	payload["updated_at"] = FormatDate(updatedAt)
+
+	// Scrub free text PII
+	m.scrubber.Apply(event.Name, payload)
+
+	// Anonymize
+	err = m.anonymizer.Apply(event.Name, updatedAt, payload)
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "invalid updated_at")
+		return clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(ctx, hostStates), "failed to anonymize payload")
 	}
 
-	payload["updated_at"] = FormatDate(updatedAt)
-
-	// Anonymize user_name
-	hashedUser, err := HashValue(payload, "user_name")
+	// Compute cluster_state_id, from the cluster payload only
+	clusterStateID, err := m.hashPayload(event.Payload)
 	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "failed to hash user_name")
+		return clusterStateInputs{}, common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(ctx, hostStates), "failed to compute cluster state id")
 	}
 
-	if hashedUser != "" {
-		payload["user_id"] = hashedUser
+	payload["cluster_state_id"] = clusterStateID
+
+	ret := clusterStateInputs{
+		ID:        clusterStateID,
+		ClusterID: clusterID,
+		UpdatedAt: updatedAt,
+		Payload:   payload,
 	}
 
-	delete(payload, "user_name")
+	return ret, nil
+}
 
-	// Compute cluster_state_id
-	clusterStateID, err := HashPayload(event.Payload)
-	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, m.makeInputsFromHostStates(hostStates), "failed to compute cluster state id")
+func (m Main) writeClusterState(ctx context.Context, inputs clusterStateInputs, hostStates []entity.HostState) (entity.ProjectedClusterState, error) {
+	hosts := make([]interface{}, 0, len(hostStates))
+	for _, hs := range hostStates {
+		hosts = append(hosts, hs.Payload)
 	}
 
-	payload["cluster_state_id"] = clusterStateID
+	payload := CopyPayload(inputs.Payload)
+	payload["hosts"] = hosts
 
 	// Create ClusterState
 	clusterState := entity.ProjectedClusterState{
-		ID:        clusterStateID,
-		Timestamp: updatedAt,
+		ID:        inputs.ID,
+		Timestamp: inputs.UpdatedAt,
 		Payload:   payload,
+		Stream:    streamOf(eventNameClusterState),
 	}
 
 	// Store
-	err = m.projectionWriter.WriteProjectedClusterState(ctx, clusterState)
+	err := m.projectionWriter.WriteProjectedClusterState(ctx, clusterState)
 	if err != nil {
 		// If the error is already a processing error, keep the category and add the host states as additional inputs
-		inputs := m.makeInputsFromHostStates(hostStates)
+		inputs := m.makeInputsFromHostStates(ctx, hostStates)
 		category := categoryErrHostWriterRepo
 		pErr := pipeline.ErrProcessingError{}
 
@@ -109,14 +186,14 @@ func (m Main) processClusterState(ctx context.Context, event entity.Event) error
 			category = pErr.Category
 		}
 
-		return common.NewErrProcessingError(err, category, inputs, "failed to write cluster state")
+		return entity.ProjectedClusterState{}, common.NewErrProcessingError(err, category, inputs, "failed to write cluster state")
 	}
 
-	return nil
+	return clusterState, nil
 }
 
-func (m Main) makeInputsFromHostStates(states []entity.HostState) []pipeline.Input {
-	logger := log.Logger()
+func (m Main) makeInputsFromHostStates(ctx context.Context, states []entity.HostState) []pipeline.Input {
+	logger := log.FromContext(ctx)
 
 	ret := make([]pipeline.Input, 0, len(states))
 
@@ -2,16 +2,29 @@ package processing
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/openshift-assisted/ccx-exporter/internal/common"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
 )
 
 const categoryErrInvalidHostEvent = "invalid_host_event"
 
+func init() {
+	// updated_at is optional for host states: they are not counted as late data
+	RegisterEventType(EventType{
+		Name:       eventNameHostState,
+		Handler:    Main.processHostState,
+		LatePolicy: LatePolicyIgnore,
+		Stream:     StreamHosts,
+	})
+}
+
 func (m Main) processHostState(ctx context.Context, event entity.Event) error {
 	// Extract Mandatory fields (cluster_id, id)
 	clusterID, err := ExtractString(event.Payload, "cluster_id")
@@ -26,20 +39,8 @@ func (m Main) processHostState(ctx context.Context, event entity.Event) error {
 
 	payload := CopyPayload(event.Payload)
 
-	// Anonymize user_name
-	hashedUser, err := HashValue(payload, "user_name")
-	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidHostEvent, nil, "failed to hash user_name")
-	}
-
-	if hashedUser != "" {
-		payload["user_id"] = hashedUser
-	}
-
-	delete(payload, "user_name")
-
 	// Rename & "jsonify" inventory -> host_inventory
-	inventory, err := computeHostInventory(payload["inventory"])
+	inventory, err := computeHostInventory(payload["inventory"], m.useNumber)
 	if err != nil {
 		return err
 	}
@@ -47,27 +48,90 @@ func (m Main) processHostState(ctx context.Context, event entity.Event) error {
 	payload["host_inventory"] = inventory
 	delete(payload, "inventory")
 
-	// Drop free_addresses (not push by scraper and quite big for not that much value)
-	delete(payload, "free_addresses")
+	// updated_at is optional: zero when missing or invalid
+	_, updatedAt, updatedAtErr := m.dateParser.Extract(event.Payload, "updated_at")
+	if updatedAtErr != nil {
+		updatedAt = time.Time{}
+	}
+
+	// Scrub free text PII
+	m.scrubber.Apply(event.Name, payload)
 
-	// Create HostState
+	// Anonymize, after the inventory conversion so rules can target host_inventory fields
+	err = m.anonymizer.Apply(event.Name, updatedAt, payload)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidHostEvent, nil, "failed to anonymize payload")
+	}
+
+	// Create HostState, ordered by updated_at when available
 	hostState := entity.HostState{
 		ClusterID: clusterID,
 		HostID:    hostID,
 		Metadata:  CopyPayload(event.Metadata),
 		Payload:   payload,
+		UpdatedAt: updatedAt,
 	}
 
-	// Store
+	// Store, a more recent host state is kept
 	err = m.hostRepo.WriteHostState(ctx, hostState)
-	if err != nil {
+
+	outdated := errors.Is(err, repo.ErrOutdated)
+	if outdated {
+		log.FromContext(ctx).V(1).Info("Outdated host state not stored", "clusterID", clusterID, "hostID", hostID)
+	} else if err != nil {
 		return fmt.Errorf("failed to write host state: %w", err)
 	}
 
+	// Project, after storing: a retry stores the same state again. Host states without updated_at are only stored.
+	switch {
+	case errors.Is(updatedAtErr, errMissingKey):
+		log.FromContext(ctx).V(2).Info("Host state without updated_at not projected")
+	case updatedAtErr != nil:
+		return common.NewErrProcessingError(updatedAtErr, categoryErrInvalidHostEvent, nil, "invalid updated_at")
+	default:
+		err = m.projectHostState(ctx, event, payload, updatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Nothing changed for the cluster
+	if outdated {
+		return nil
+	}
+
+	// Cluster state projected before this host state
+	return m.reemitClusterState(ctx, clusterID)
+}
+
+// projectHostState writes the host history, updatedAt is the parsed updated_at of the event
+func (m Main) projectHostState(ctx context.Context, event entity.Event, payload map[string]interface{}, updatedAt time.Time) error {
+	hostStateID, err := m.hashPayload(event.Payload)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidHostEvent, nil, "failed to compute host state id")
+	}
+
+	// Stored payload is embedded in cluster states as is: projected fields are added to a copy
+	projectedPayload := CopyPayload(payload)
+	projectedPayload["updated_at"] = FormatDate(updatedAt)
+	projectedPayload["host_state_id"] = hostStateID
+
+	hostState := entity.ProjectedHostState{
+		ID:        hostStateID,
+		Timestamp: updatedAt,
+		Payload:   projectedPayload,
+		Stream:    streamOf(eventNameHostState),
+	}
+
+	err = m.projectionWriter.WriteProjectedHostState(ctx, hostState)
+	if err != nil {
+		return fmt.Errorf("failed to write projected host state: %w", err)
+	}
+
 	return nil
 }
 
-func computeHostInventory(input interface{}) (interface{}, error) {
+func computeHostInventory(input interface{}, useNumber bool) (interface{}, error) {
 	if input == nil {
 		return nil, nil
 	}
@@ -83,7 +147,7 @@ func computeHostInventory(input interface{}) (interface{}, error) {
 
 	ret := make(map[string]interface{})
 
-	err = json.Unmarshal(inventory, &ret)
+	err = unmarshalJSON(inventory, &ret, useNumber)
 	if err != nil {
 		return nil, common.NewErrProcessingError(err, categoryErrInvalidHostEvent, nil, "failed to unmarshal inventory")
 	}
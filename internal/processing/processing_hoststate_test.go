@@ -0,0 +1,174 @@
+package processing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+func TestProcessHostStateProjection(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter)
+
+	event := entity.Event{
+		Name: "HostState",
+		Payload: map[string]interface{}{
+			"cluster_id": "cluster-1",
+			"id":         "host-1",
+			"status":     "known",
+			"user_name":  "john",
+			"updated_at": "2025-02-03T21:02:45.465421Z",
+		},
+	}
+
+	expectedID, err := processing.HashPayload(event.Payload)
+	require.NoError(t, err, "failed to hash payload")
+
+	storedPayload := map[string]interface{}{
+		"cluster_id":     "cluster-1",
+		"id":             "host-1",
+		"status":         "known",
+		"user_id":        "527bd5b5d689e2c32ae974c6229ff785",
+		"updated_at":     "2025-02-03T21:02:45.465421Z",
+		"host_inventory": nil,
+	}
+
+	gomock.InOrder(
+		hostRepo.EXPECT().WriteHostState(gomock.Any(), entity.HostState{
+			ClusterID: "cluster-1",
+			HostID:    "host-1",
+			Payload:   storedPayload,
+			Metadata:  map[string]interface{}{},
+			UpdatedAt: time.Date(2025, 2, 3, 21, 2, 45, 465421000, time.UTC),
+		}).Return(nil),
+		projectionWriter.EXPECT().WriteProjectedHostState(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, state entity.ProjectedHostState) error {
+				assert.Equal(t, expectedID, state.ID)
+				assert.Equal(t, time.Date(2025, 2, 3, 21, 2, 45, 465421000, time.UTC), state.Timestamp)
+				assert.Equal(t, expectedID, state.Payload["host_state_id"])
+				assert.Equal(t, "known", state.Payload["status"])
+				assert.NotContains(t, state.Payload, "user_name")
+
+				return nil
+			}),
+	)
+
+	err = main.Process(context.Background(), event)
+	require.NoError(t, err, "failed to process host state")
+
+	// Stored payload is not modified by the projection
+	assert.NotContains(t, storedPayload, "host_state_id")
+}
+
+func TestProcessHostStateWithoutUpdatedAt(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter)
+
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := main.Process(context.Background(), entity.Event{
+		Name:    "HostState",
+		Payload: map[string]interface{}{"cluster_id": "cluster-1", "id": "host-1"},
+	})
+	require.NoError(t, err, "host states without updated_at are only stored")
+}
+
+func TestProcessHostStateInvalidUpdatedAt(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter)
+
+	// Stored unordered, not projected
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, state entity.HostState) error {
+			assert.True(t, state.UpdatedAt.IsZero(), "invalid updated_at should not order the write")
+
+			return nil
+		})
+
+	err := main.Process(context.Background(), entity.Event{
+		Name:    "HostState",
+		Payload: map[string]interface{}{"cluster_id": "cluster-1", "id": "host-1", "updated_at": "yesterday"},
+	})
+	assert.Error(t, err, "invalid updated_at should be rejected")
+}
+
+func TestProcessHostStateOutdated(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter)
+
+	// Still projected, the cluster state is not re-emitted
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).Return(repo.ErrOutdated)
+	projectionWriter.EXPECT().WriteProjectedHostState(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := main.Process(context.Background(), entity.Event{
+		Name: "HostState",
+		Payload: map[string]interface{}{
+			"cluster_id": "cluster-1",
+			"id":         "host-1",
+			"updated_at": "2025-02-03T21:02:45.465421Z",
+		},
+	})
+	require.NoError(t, err, "outdated host states are skipped")
+}
+
+func TestProcessHostStateUseNumber(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter).WithUseNumber(true)
+
+	hostRepo.EXPECT().WriteHostState(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, state entity.HostState) error {
+			inventory, ok := state.Payload["host_inventory"].(map[string]interface{})
+			require.True(t, ok, "inventory should be decoded")
+
+			disks, ok := inventory["disks"].([]interface{})
+			require.True(t, ok, "disks should be decoded")
+			assert.Equal(t, json.Number("9007199254740993"), disks[0].(map[string]interface{})["size_bytes"])
+
+			return nil
+		})
+
+	err := main.Process(context.Background(), entity.Event{
+		Name: "HostState",
+		Payload: map[string]interface{}{
+			"cluster_id": "cluster-1",
+			"id":         "host-1",
+			"inventory":  `{"disks":[{"size_bytes":9007199254740993}]}`,
+		},
+	})
+	require.NoError(t, err, "failed to process host state")
+}
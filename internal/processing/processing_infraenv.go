@@ -10,14 +10,19 @@ import (
 
 const categoryErrInvalidInfraEnvEvent = "invalid_infraenv_event"
 
+func init() {
+	RegisterEventType(EventType{
+		Name:       eventNameInfraEnvState,
+		Handler:    Main.processInfraEnv,
+		TimeField:  "updated_at",
+		LatePolicy: LatePolicyCount,
+		Stream:     StreamInfraEnvs,
+	})
+}
+
 func (m Main) processInfraEnv(ctx context.Context, event entity.Event) error {
 	// Check updated_at
-	updatedAtStr, err := ExtractString(event.Payload, "updated_at")
-	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidInfraEnvEvent, nil, "failed to extract updated_at")
-	}
-
-	updatedAt, err := ValidateDate(updatedAtStr)
+	_, updatedAt, err := m.dateParser.Extract(event.Payload, "updated_at")
 	if err != nil {
 		return common.NewErrProcessingError(err, categoryErrInvalidInfraEnvEvent, nil, "invalid format for updated_at")
 	}
@@ -25,20 +30,17 @@ func (m Main) processInfraEnv(ctx context.Context, event entity.Event) error {
 	payload := CopyPayload(event.Payload)
 	payload["updated_at"] = FormatDate(updatedAt)
 
-	// Anonymize user_name
-	hashedUser, err := HashValue(payload, "user_name")
-	if err != nil {
-		return common.NewErrProcessingError(err, categoryErrInvalidInfraEnvEvent, nil, "failed to hash user_name")
-	}
+	// Scrub free text PII
+	m.scrubber.Apply(event.Name, payload)
 
-	if hashedUser != "" {
-		payload["user_id"] = hashedUser
+	// Anonymize
+	err = m.anonymizer.Apply(event.Name, updatedAt, payload)
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidInfraEnvEvent, nil, "failed to anonymize payload")
 	}
 
-	delete(payload, "user_name")
-
 	// Add infraenv_state_id
-	infraEnvStateID, err := HashPayload(event.Payload)
+	infraEnvStateID, err := m.hashPayload(event.Payload)
 	if err != nil {
 		return common.NewErrProcessingError(err, categoryErrInvalidInfraEnvEvent, nil, "failed to compute infraenv state id")
 	}
@@ -50,6 +52,7 @@ func (m Main) processInfraEnv(ctx context.Context, event entity.Event) error {
 		ID:        infraEnvStateID,
 		Timestamp: updatedAt,
 		Payload:   payload,
+		Stream:    streamOf(eventNameInfraEnvState),
 	}
 
 	// Store
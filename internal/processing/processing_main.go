@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
 	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
@@ -17,34 +18,98 @@ const (
 	eventNameInfraEnvState = "InfraEnv"
 
 	categoryUnknownEventName = "unknown_name"
+
+	stageHash = "hash"
 )
 
 type Main struct {
 	hostRepo         repo.HostState
 	projectionWriter repo.ProjectionWriter
+	anonymizer       anonymization.Engine
+	scrubber         anonymization.Scrubber
+	dateParser       DateParser
+	fallback         pipeline.Processing[entity.Event]
+	debounce         *debounce              // nil when disabled
+	summaries        *summaries             // nil when disabled
+	changes          *changes               // nil when disabled
+	stages           *pipeline.StageMetrics // nil when disabled
+	useNumber        bool
 }
 
 func NewMain(hostRepo repo.HostState, projectionWriter repo.ProjectionWriter) Main {
 	return Main{
 		hostRepo:         hostRepo,
 		projectionWriter: projectionWriter,
+		anonymizer:       anonymization.NewDefaultEngine(),
 	}
 }
 
+// WithAnonymizer replaces the default anonymization rules
+func (m Main) WithAnonymizer(anonymizer anonymization.Engine) Main {
+	m.anonymizer = anonymizer
+
+	return m
+}
+
+// WithScrubber redacts PII from free text fields, nothing is scrubbed by default
+func (m Main) WithScrubber(scrubber anonymization.Scrubber) Main {
+	m.scrubber = scrubber
+
+	return m
+}
+
+// WithDateParser accepts other date formats than the legacy one, dates are still projected with FormatDate
+func (m Main) WithDateParser(dateParser DateParser) Main {
+	m.dateParser = dateParser
+
+	return m
+}
+
+// WithFallback processes the events without registered event type, instead of failing with unknown_name
+func (m Main) WithFallback(fallback pipeline.Processing[entity.Event]) Main {
+	m.fallback = fallback
+
+	return m
+}
+
+// WithUseNumber decodes the numbers of the host inventories & of the stored states as json.Number.
+// It must match the event decoding, see pipeline.Runner.WithUseNumber.
+func (m Main) WithUseNumber(useNumber bool) Main {
+	m.useNumber = useNumber
+
+	return m
+}
+
+// WithStageMetrics measures the hashing of the payloads, see pipeline.StageMetrics
+func (m Main) WithStageMetrics(stages *pipeline.StageMetrics) Main {
+	m.stages = stages
+
+	return m
+}
+
 func (m Main) Process(processingCtx context.Context, event entity.Event) error {
 	ctx, cancel := context.WithTimeout(processingCtx, 4*time.Second)
 	defer cancel()
 
-	switch event.Name {
-	case eventNameEvent:
-		return m.processClusterEvent(ctx, event)
-	case eventNameClusterState:
-		return m.processClusterState(ctx, event)
-	case eventNameHostState:
-		return m.processHostState(ctx, event)
-	case eventNameInfraEnvState:
-		return m.processInfraEnv(ctx, event)
-	default:
+	eventType, found := LookupEventType(event.Name)
+	if !found {
+		if m.fallback != nil {
+			return m.fallback.Process(ctx, event)
+		}
+
 		return pipeline.NewErrProcessingError(fmt.Errorf("unknown event name: %s", event.Name), categoryUnknownEventName, nil)
 	}
+
+	return eventType.Handler(m, ctx, event)
+}
+
+// hashPayload is HashPayload measured as the hash stage
+func (m Main) hashPayload(payload map[string]interface{}) (string, error) {
+	end := m.stages.Start(stageHash)
+
+	ret, err := HashPayload(payload)
+
+	end(err)
+
+	return ret, err
 }
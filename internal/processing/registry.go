@@ -0,0 +1,101 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+)
+
+// Handler processes the events of one type. Handlers are registered as method expressions, e.g. Main.processHostState.
+type Handler func(m Main, ctx context.Context, event entity.Event) error
+
+type LatePolicy int
+
+const (
+	// LatePolicyCount counts the events older than the CCX processing deadline
+	LatePolicyCount LatePolicy = iota
+	// LatePolicyIgnore never counts events as late, e.g. for events stored before being projected
+	LatePolicyIgnore
+)
+
+// Output streams, suffix of the s3 prefix
+const (
+	StreamEvents    = "events"
+	StreamClusters  = "clusters"
+	StreamInfraEnvs = "infra_envs"
+	StreamHosts     = "hosts"
+
+	// Derived from the cluster states, not registered by an event type
+	StreamSummaries = "cluster_summaries"
+	StreamChanges   = "cluster_changes"
+)
+
+// EventType describes how an event type is processed
+type EventType struct {
+	Name       string
+	Handler    Handler
+	TimeField  string // payload field holding the event time, empty when the event type has none
+	LatePolicy LatePolicy
+	Stream     string // output stream of the projections
+}
+
+const unknownEventNameLabel = "unknown"
+
+var eventTypes = map[string]EventType{}
+
+// RegisterEventType registers an event type, it is meant to be called from init functions.
+func RegisterEventType(eventType EventType) {
+	if eventType.Name == "" || eventType.Handler == nil {
+		panic("event type name & handler are mandatory")
+	}
+
+	if _, found := eventTypes[eventType.Name]; found {
+		panic(fmt.Sprintf("event type %s already registered", eventType.Name))
+	}
+
+	eventTypes[eventType.Name] = eventType
+}
+
+func LookupEventType(name string) (EventType, bool) {
+	ret, found := eventTypes[name]
+
+	return ret, found
+}
+
+// LateStreams returns the output streams of the event types counted as late, they are the only ones routed as late
+func LateStreams() map[string]struct{} {
+	ret := make(map[string]struct{})
+
+	for _, eventType := range eventTypes {
+		if eventType.LatePolicy == LatePolicyCount && eventType.Stream != "" {
+			ret[eventType.Stream] = struct{}{}
+		}
+	}
+
+	return ret
+}
+
+// streamOf returns the output stream of a registered event type
+func streamOf(name string) string {
+	return eventTypes[name].Stream
+}
+
+// EventNameLabel is the event name as a metric label, unknown names are grouped to bound the cardinality
+func EventNameLabel(event entity.Event) string {
+	if _, found := eventTypes[event.Name]; !found {
+		return unknownEventNameLabel
+	}
+
+	return event.Name
+}
+
+// IgnoreEvent is a fallback dropping unknown events
+type IgnoreEvent struct{}
+
+func (IgnoreEvent) Process(ctx context.Context, event entity.Event) error {
+	log.FromContext(ctx).V(2).Info("Unknown event ignored", "name", event.Name)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package processing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func TestRegisteredEventTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"ClusterState", "Event", "HostState", "InfraEnv"} {
+		_, found := processing.LookupEventType(name)
+		assert.True(t, found, "%s should be registered", name)
+	}
+
+	hostState, found := processing.LookupEventType("HostState")
+	require.True(t, found, "HostState should be registered")
+	assert.Equal(t, processing.LatePolicyIgnore, hostState.LatePolicy)
+	assert.Empty(t, hostState.TimeField)
+
+	event, found := processing.LookupEventType("Event")
+	require.True(t, found, "Event should be registered")
+	assert.Equal(t, "event_time", event.TimeField)
+	assert.Equal(t, processing.StreamEvents, event.Stream)
+
+	ts, err := processing.ExtractEventTime(entity.Event{
+		Name:    "Event",
+		Payload: map[string]interface{}{"event_time": "2024-05-06T07:08:09.123456Z"},
+	})
+	require.NoError(t, err, "failed to extract event time")
+	assert.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC), ts)
+
+	_, err = processing.ExtractEventTime(entity.Event{Name: "HostState"})
+	assert.Error(t, err, "host states have no event time")
+}
+
+func TestLateStreams(t *testing.T) {
+	t.Parallel()
+
+	// Host states are stored before being projected, summaries & changes are derived: never routed as late
+	expected := map[string]struct{}{
+		processing.StreamEvents:    {},
+		processing.StreamClusters:  {},
+		processing.StreamInfraEnvs: {},
+	}
+
+	assert.Equal(t, expected, processing.LateStreams())
+}
+
+func TestUnknownEvent(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	main := processing.NewMain(mock.NewMockHostState(ctrl), mock.NewMockProjectionWriter(ctrl))
+
+	event := entity.Event{Name: "OperatorState"}
+
+	err := main.Process(context.Background(), event)
+
+	pErr := pipeline.ErrProcessingError{}
+	require.ErrorAs(t, err, &pErr, "unknown events should fail")
+	assert.Equal(t, "unknown_name", pErr.Category)
+
+	err = main.WithFallback(processing.IgnoreEvent{}).Process(context.Background(), event)
+	assert.NoError(t, err, "unknown events should be ignored")
+}
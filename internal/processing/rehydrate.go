@@ -0,0 +1,253 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+)
+
+// Rehydrate rebuilds host states from the hosts embedded in cluster projections.
+// It's meant to be used after a valkey data loss, while the processing is running:
+// a host state already present in valkey is never replaced by an older one (conditional write, see
+// host.ValkeyRepo.WithBackfill). Projections are read day by day: only the candidates of a day are kept in memory.
+type Rehydrate struct {
+	reader   repo.ProjectedClusterStateReader
+	hostRepo repo.HostState
+	ttl      time.Duration
+	clock    clockwork.Clock
+	dryRun   bool
+
+	dateParser DateParser
+}
+
+type RehydrateStats struct {
+	Projections    int
+	Invalid        int
+	Candidates     int
+	Written        int
+	SkippedExpired int
+	SkippedNewer   int
+}
+
+type hostCandidate struct {
+	payload   map[string]interface{}
+	updatedAt time.Time
+}
+
+func NewRehydrate(reader repo.ProjectedClusterStateReader, hostRepo repo.HostState, ttl time.Duration, clock clockwork.Clock) Rehydrate {
+	return Rehydrate{
+		reader:   reader,
+		hostRepo: hostRepo,
+		ttl:      ttl,
+		clock:    clock,
+	}
+}
+
+func (r Rehydrate) WithDryRun(dryRun bool) Rehydrate {
+	r.dryRun = dryRun
+
+	return r
+}
+
+// WithDateParser parses the host updated_at with the processing date formats
+func (r Rehydrate) WithDateParser(dateParser DateParser) Rehydrate {
+	r.dateParser = dateParser
+
+	return r
+}
+
+// Run scans the cluster projections of the last `since` duration, capped by the host states ttl.
+func (r Rehydrate) Run(ctx context.Context, since time.Duration) (RehydrateStats, error) {
+	stats := RehydrateStats{}
+
+	now := r.clock.Now().UTC()
+	limit := now.Add(-r.ttl)
+
+	from := now.Add(-since)
+	if from.Before(limit) {
+		from = limit
+	}
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+
+	for !day.After(now) {
+		err := r.rehydrateDay(ctx, day, limit, &stats)
+		if err != nil {
+			return stats, err
+		}
+
+		day = day.Add(24 * time.Hour)
+	}
+
+	return stats, nil
+}
+
+// rehydrateDay writes the most recent version of each host found in the projections of a day, cluster by cluster.
+// A host updated on several days is written once per day, the conditional write keeps the most recent one.
+func (r Rehydrate) rehydrateDay(ctx context.Context, day time.Time, limit time.Time, stats *RehydrateStats) error {
+	candidates := make(map[string]map[string]hostCandidate)
+
+	err := r.reader.ReadProjectedClusterStates(ctx, day, day, func(state entity.ProjectedClusterState) error {
+		stats.Projections++
+
+		r.collect(candidates, state, limit, stats)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read cluster states: %w", err)
+	}
+
+	clusterIDs := make([]string, 0, len(candidates))
+	for clusterID := range candidates {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+
+	sort.Strings(clusterIDs)
+
+	for _, clusterID := range clusterIDs {
+		err := r.rehydrateCluster(ctx, clusterID, candidates[clusterID], stats)
+		if err != nil {
+			return fmt.Errorf("failed to rehydrate cluster %s: %w", clusterID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r Rehydrate) collect(candidates map[string]map[string]hostCandidate, state entity.ProjectedClusterState, limit time.Time, stats *RehydrateStats) {
+	clusterID, err := ExtractString(state.Payload, "id")
+	if err != nil {
+		stats.Invalid++
+
+		return
+	}
+
+	hosts, ok := state.Payload["hosts"].([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, h := range hosts {
+		payload, ok := h.(map[string]interface{})
+		if !ok {
+			stats.Invalid++
+
+			continue
+		}
+
+		hostID, err := ExtractString(payload, "id")
+		if err != nil {
+			stats.Invalid++
+
+			continue
+		}
+
+		updatedAt := r.hostUpdatedAt(payload, state.Timestamp)
+
+		if updatedAt.Before(limit) {
+			stats.SkippedExpired++
+
+			continue
+		}
+
+		if candidates[clusterID] == nil {
+			candidates[clusterID] = make(map[string]hostCandidate)
+		}
+
+		current, found := candidates[clusterID][hostID]
+		if found && !updatedAt.After(current.updatedAt) {
+			continue
+		}
+
+		candidates[clusterID][hostID] = hostCandidate{
+			payload:   payload,
+			updatedAt: updatedAt,
+		}
+	}
+}
+
+func (r Rehydrate) rehydrateCluster(ctx context.Context, clusterID string, candidates map[string]hostCandidate, stats *RehydrateStats) error {
+	hostIDs := make([]string, 0, len(candidates))
+	for hostID := range candidates {
+		hostIDs = append(hostIDs, hostID)
+	}
+
+	sort.Strings(hostIDs)
+
+	if r.dryRun {
+		return r.countCluster(ctx, clusterID, hostIDs, candidates, stats)
+	}
+
+	for _, hostID := range hostIDs {
+		candidate := candidates[hostID]
+		stats.Candidates++
+
+		// Conditional write: skipped if a more recent version is stored
+		err := r.hostRepo.WriteHostState(ctx, entity.HostState{
+			ClusterID: clusterID,
+			HostID:    hostID,
+			Payload:   candidate.payload,
+			UpdatedAt: candidate.updatedAt,
+		})
+		if errors.Is(err, repo.ErrOutdated) {
+			stats.SkippedNewer++
+
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to write host state %s: %w", hostID, err)
+		}
+
+		stats.Written++
+	}
+
+	return nil
+}
+
+// countCluster estimates the writes of a dry run from the stored host states
+func (r Rehydrate) countCluster(ctx context.Context, clusterID string, hostIDs []string, candidates map[string]hostCandidate, stats *RehydrateStats) error {
+	existingStates, err := r.hostRepo.GetHostStates(ctx, clusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get host states: %w", err)
+	}
+
+	existing := make(map[string]time.Time, len(existingStates))
+	for _, hs := range existingStates {
+		// Zero time if not parsable: existing data is kept
+		existing[hs.HostID] = r.hostUpdatedAt(hs.Payload, time.Time{})
+	}
+
+	for _, hostID := range hostIDs {
+		stats.Candidates++
+
+		existingUpdatedAt, found := existing[hostID]
+		if found && (existingUpdatedAt.IsZero() || existingUpdatedAt.After(candidates[hostID].updatedAt)) {
+			stats.SkippedNewer++
+
+			continue
+		}
+
+		stats.Written++
+	}
+
+	return nil
+}
+
+// hostUpdatedAt returns the updated_at field of a host payload, or fallback if missing or invalid
+func (r Rehydrate) hostUpdatedAt(payload map[string]interface{}, fallback time.Time) time.Time {
+	_, ret, err := r.dateParser.Extract(payload, "updated_at")
+	if err != nil {
+		return fallback
+	}
+
+	return ret
+}
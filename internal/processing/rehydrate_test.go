@@ -0,0 +1,160 @@
+package processing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+)
+
+func clusterProjection(clusterID string, hosts ...map[string]interface{}) entity.ProjectedClusterState {
+	hostList := make([]interface{}, 0, len(hosts))
+	for _, h := range hosts {
+		hostList = append(hostList, h)
+	}
+
+	return entity.ProjectedClusterState{
+		ID:      "abcdef",
+		Payload: map[string]interface{}{"id": clusterID, "hosts": hostList},
+	}
+}
+
+func hostPayload(hostID, updatedAt string) map[string]interface{} {
+	return map[string]interface{}{"id": hostID, "updated_at": updatedAt}
+}
+
+// readByDay serves the projections of each day, as the s3 reader does
+func readByDay(projections map[time.Time][]entity.ProjectedClusterState) func(context.Context, time.Time, time.Time, func(entity.ProjectedClusterState) error) error {
+	return func(_ context.Context, from, _ time.Time, fn func(entity.ProjectedClusterState) error) error {
+		for _, p := range projections[from] {
+			err := fn(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestRehydrate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	reader := mock.NewMockProjectedClusterStateReader(ctrl)
+	hostRepo := mock.NewMockHostState(ctrl)
+
+	ctx := context.Background()
+	now := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+
+	projections := map[time.Time][]entity.ProjectedClusterState{
+		time.Date(2025, 2, 9, 0, 0, 0, 0, time.UTC): {
+			clusterProjection("cluster-1",
+				hostPayload("host-1", "2025-02-09T10:00:00.000Z"),
+				hostPayload("host-2", "2025-02-09T10:00:00.000Z"),
+			),
+			// More recent version of host-1
+			clusterProjection("cluster-1",
+				hostPayload("host-1", "2025-02-09T11:00:00.000Z"),
+			),
+			// Expired
+			clusterProjection("cluster-2",
+				hostPayload("host-3", "2025-01-01T10:00:00.000Z"),
+			),
+			// Invalid
+			{ID: "abcdef", Payload: map[string]interface{}{"hosts": []interface{}{}}},
+		},
+		time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC): {
+			clusterProjection("cluster-1",
+				hostPayload("host-1", "2025-02-10T09:00:00.000Z"),
+			),
+		},
+	}
+
+	// Day by day, from the ttl limit
+	for day := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC); !day.After(now); day = day.Add(24 * time.Hour) {
+		reader.EXPECT().ReadProjectedClusterStates(gomock.Any(), day, day, gomock.Any()).DoAndReturn(readByDay(projections))
+	}
+
+	gomock.InOrder(
+		hostRepo.EXPECT().WriteHostState(gomock.Any(), entity.HostState{
+			ClusterID: "cluster-1",
+			HostID:    "host-1",
+			Payload:   hostPayload("host-1", "2025-02-09T11:00:00.000Z"),
+			UpdatedAt: time.Date(2025, 2, 9, 11, 0, 0, 0, time.UTC),
+		}).Return(nil),
+		// host-2 is already stored with a more recent version
+		hostRepo.EXPECT().WriteHostState(gomock.Any(), entity.HostState{
+			ClusterID: "cluster-1",
+			HostID:    "host-2",
+			Payload:   hostPayload("host-2", "2025-02-09T10:00:00.000Z"),
+			UpdatedAt: time.Date(2025, 2, 9, 10, 0, 0, 0, time.UTC),
+		}).Return(repo.ErrOutdated),
+		hostRepo.EXPECT().WriteHostState(gomock.Any(), entity.HostState{
+			ClusterID: "cluster-1",
+			HostID:    "host-1",
+			Payload:   hostPayload("host-1", "2025-02-10T09:00:00.000Z"),
+			UpdatedAt: time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC),
+		}).Return(nil),
+	)
+
+	r := processing.NewRehydrate(reader, hostRepo, 7*24*time.Hour, clockwork.NewFakeClockAt(now))
+
+	stats, err := r.Run(ctx, 30*24*time.Hour)
+	require.NoError(t, err, "rehydration failed")
+
+	assert.Equal(t, processing.RehydrateStats{
+		Projections:    5,
+		Invalid:        1,
+		Candidates:     3,
+		Written:        2,
+		SkippedExpired: 1,
+		SkippedNewer:   1,
+	}, stats)
+}
+
+func TestRehydrateDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	reader := mock.NewMockProjectedClusterStateReader(ctrl)
+	hostRepo := mock.NewMockHostState(ctrl)
+
+	now := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	reader.EXPECT().ReadProjectedClusterStates(gomock.Any(), today, today, gomock.Any()).DoAndReturn(readByDay(map[time.Time][]entity.ProjectedClusterState{
+		today: {
+			clusterProjection("cluster-1",
+				hostPayload("host-1", "2025-02-10T10:00:00.000Z"),
+				hostPayload("host-2", "2025-02-10T10:00:00.000Z"),
+			),
+		},
+	}))
+
+	// Nothing is written, host-2 is already stored with a more recent version
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return([]entity.HostState{
+		{ClusterID: "cluster-1", HostID: "host-2", Payload: hostPayload("host-2", "2025-02-10T11:00:00.000Z")},
+	}, nil)
+
+	r := processing.NewRehydrate(reader, hostRepo, 7*24*time.Hour, clockwork.NewFakeClockAt(now)).WithDryRun(true)
+
+	stats, err := r.Run(context.Background(), time.Hour)
+	require.NoError(t, err, "rehydration failed")
+
+	assert.Equal(t, processing.RehydrateStats{
+		Projections:  1,
+		Candidates:   2,
+		Written:      1,
+		SkippedNewer: 1,
+	}, stats)
+}
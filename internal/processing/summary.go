@@ -0,0 +1,308 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/common"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo"
+	"github.com/openshift-assisted/ccx-exporter/internal/log"
+)
+
+const (
+	stateKindClusterSummary = "cluster_summary"
+
+	clusterStatusInstalled = "installed"
+	clusterStatusError     = "error"
+	clusterStatusCancelled = "cancelled"
+
+	maxSummaryUpdateAttempts = 3
+)
+
+var (
+	errSummaryConflict = errors.New("cluster summary updated concurrently")
+
+	// Statuses of a cluster being installed: the last one is the failed stage when the installation fails
+	installationStages = map[string]struct{}{
+		"preparing-for-installation":     {},
+		"installing":                     {},
+		"installing-pending-user-action": {},
+		"finalizing":                     {},
+	}
+
+	terminalStatuses = map[string]struct{}{
+		clusterStatusInstalled: {},
+		clusterStatusError:     {},
+		clusterStatusCancelled: {},
+	}
+
+	errorSeverities = map[string]struct{}{
+		"error":    {},
+		"critical": {},
+	}
+)
+
+type summaries struct {
+	store repo.StateStore
+	ttl   time.Duration
+}
+
+// clusterSummary is the lifecycle of the current installation of a cluster, updated by events & cluster states
+type clusterSummary struct {
+	ClusterID          string    `json:"cluster_id"`
+	InstallStartedAt   string    `json:"install_started_at,omitempty"`
+	InstallCompletedAt string    `json:"install_completed_at,omitempty"`
+	Status             string    `json:"status,omitempty"`
+	LastStage          string    `json:"last_stage,omitempty"`
+	FailureReason      string    `json:"failure_reason,omitempty"`
+	LastErrorEvent     string    `json:"last_error_event,omitempty"`
+	HostCount          int       `json:"host_count"`
+	OpenshiftVersion   string    `json:"openshift_version,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Emitted            bool      `json:"emitted"`
+}
+
+// WithClusterSummaries emits a summary of the installation when a cluster reaches a terminal status.
+// Summaries are stored incrementally in store, ttl after their last update.
+func (m Main) WithClusterSummaries(store repo.StateStore, ttl time.Duration) Main {
+	m.summaries = &summaries{
+		store: store,
+		ttl:   ttl,
+	}
+
+	return m
+}
+
+// updateSummaryFromClusterState updates the summary of the cluster, updatedAt is the parsed updated_at of the event
+func (m Main) updateSummaryFromClusterState(ctx context.Context, clusterID string, event entity.Event, updatedAt time.Time) error {
+	if m.summaries == nil {
+		return nil
+	}
+
+	// Free text fields (status_info) are scrubbed like in the cluster state projection
+	payload := CopyPayload(event.Payload)
+	m.scrubber.Apply(event.Name, payload)
+
+	return m.updateSummary(ctx, clusterID, func(summary *clusterSummary) (bool, error) {
+		// Cluster states are not ordered: an older one must not replace the status
+		if updatedAt.Before(summary.UpdatedAt) {
+			log.FromContext(ctx).V(1).Info("Outdated cluster state, summary not updated", "cluster_id", clusterID)
+
+			return false, nil
+		}
+
+		// A new installation (reset then install again) starts a new summary
+		installStartedAt := m.extractOptionalDate(payload, "install_started_at")
+		if installStartedAt != "" && installStartedAt != summary.InstallStartedAt {
+			*summary = clusterSummary{
+				ClusterID:        clusterID,
+				InstallStartedAt: installStartedAt,
+			}
+		}
+
+		status, _ := ExtractString(payload, "status")
+		if status != "" {
+			summary.Status = status
+		}
+
+		if _, found := installationStages[status]; found {
+			summary.LastStage = status
+		}
+
+		if completedAt := m.extractOptionalDate(payload, "install_completed_at"); completedAt != "" {
+			summary.InstallCompletedAt = completedAt
+		}
+
+		if version, _ := ExtractString(payload, "openshift_version"); version != "" {
+			summary.OpenshiftVersion = version
+		}
+
+		if count, ok := extractInt(payload, "total_host_count"); ok {
+			summary.HostCount = count
+		}
+
+		if status == clusterStatusError || status == clusterStatusCancelled {
+			statusInfo, _ := ExtractString(payload, "status_info")
+			summary.FailureReason = statusInfo
+		}
+
+		summary.UpdatedAt = updatedAt
+
+		// Emitted before being stored: a summary emitted again after a conflict replaces the previous one (same id)
+		_, terminal := terminalStatuses[status]
+		if terminal && !summary.Emitted {
+			err := m.emitSummary(ctx, *summary)
+			if err != nil {
+				return false, err
+			}
+
+			summary.Emitted = true
+		}
+
+		return true, nil
+	})
+}
+
+// updateSummaryFromEvent keeps the last error event, used as failure reason when the cluster state has none
+func (m Main) updateSummaryFromEvent(ctx context.Context, clusterID string, payload map[string]interface{}) error {
+	if m.summaries == nil {
+		return nil
+	}
+
+	severity, _ := ExtractString(payload, "severity")
+	if _, found := errorSeverities[severity]; !found {
+		return nil
+	}
+
+	return m.updateSummary(ctx, clusterID, func(summary *clusterSummary) (bool, error) {
+		if summary.Emitted {
+			return false, nil
+		}
+
+		summary.LastErrorEvent, _ = ExtractString(payload, "message")
+
+		return true, nil
+	})
+}
+
+// updateSummary applies update to the stored summary with a conditional write: the read-modify-write is retried
+// when the summary is updated concurrently. update returns false when the summary is unchanged.
+func (m Main) updateSummary(ctx context.Context, clusterID string, update func(summary *clusterSummary) (bool, error)) error {
+	for i := 0; i < maxSummaryUpdateAttempts; i++ {
+		summary, previous, err := m.getSummary(ctx, clusterID)
+		if err != nil {
+			return err
+		}
+
+		changed, err := update(&summary)
+		if err != nil || !changed {
+			return err
+		}
+
+		value, err := json.Marshal(summary)
+		if err != nil {
+			return common.NewErrProcessingError(err, categoryErrStateStore, nil, "failed to marshal cluster summary")
+		}
+
+		set, err := m.summaries.store.CompareAndSetState(ctx, stateKindClusterSummary, clusterID, previous, value, m.summaries.ttl)
+		if err != nil {
+			return fmt.Errorf("failed to store cluster summary: %w", err)
+		}
+
+		if set {
+			return nil
+		}
+	}
+
+	return common.NewRetryableErrProcessingError(errSummaryConflict, categoryErrStateStore, nil, "failed to update cluster summary %s", clusterID)
+}
+
+func (m Main) emitSummary(ctx context.Context, summary clusterSummary) error {
+	failureReason := summary.FailureReason
+	failedStage := ""
+
+	if summary.Status != clusterStatusInstalled {
+		failedStage = summary.LastStage
+
+		if failureReason == "" {
+			failureReason = summary.LastErrorEvent
+		}
+	}
+
+	// One summary per installation: an installation reaching a terminal status twice replaces its summary
+	summaryID, err := m.hashPayload(map[string]interface{}{
+		"cluster_id":         summary.ClusterID,
+		"install_started_at": summary.InstallStartedAt,
+	})
+	if err != nil {
+		return common.NewErrProcessingError(err, categoryErrInvalidClusterState, nil, "failed to compute cluster summary id")
+	}
+
+	payload := map[string]interface{}{
+		"cluster_summary_id":   summaryID,
+		"cluster_id":           summary.ClusterID,
+		"install_started_at":   nilIfEmpty(summary.InstallStartedAt),
+		"install_completed_at": nilIfEmpty(summary.InstallCompletedAt),
+		"final_status":         summary.Status,
+		"failed_stage":         nilIfEmpty(failedStage),
+		"failure_reason":       nilIfEmpty(failureReason),
+		"host_count":           summary.HostCount,
+		"openshift_version":    nilIfEmpty(summary.OpenshiftVersion),
+		"updated_at":           FormatDate(summary.UpdatedAt),
+	}
+
+	err = m.projectionWriter.WriteClusterSummary(ctx, entity.ClusterSummary{
+		ID:        summaryID,
+		Timestamp: summary.UpdatedAt,
+		Payload:   payload,
+		Stream:    StreamSummaries,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write cluster summary: %w", err)
+	}
+
+	return nil
+}
+
+// getSummary returns the stored summary and its raw value, nil when not stored
+func (m Main) getSummary(ctx context.Context, clusterID string) (clusterSummary, []byte, error) {
+	ret := clusterSummary{ClusterID: clusterID}
+
+	value, found, err := m.summaries.store.GetState(ctx, stateKindClusterSummary, clusterID)
+	if err != nil {
+		return ret, nil, fmt.Errorf("failed to get cluster summary: %w", err)
+	}
+
+	if !found {
+		return ret, nil, nil
+	}
+
+	err = json.Unmarshal(value, &ret)
+	if err != nil {
+		// Start again rather than blocking every message of the cluster
+		log.FromContext(ctx).Error(err, "Invalid cluster summary, reset", "cluster_id", clusterID)
+
+		return clusterSummary{ClusterID: clusterID}, value, nil
+	}
+
+	return ret, value, nil
+}
+
+// extractOptionalDate returns the formatted date, or an empty string when missing, invalid or zero (0001-01-01).
+// Only the main date of the event is counted by the date metrics.
+func (m Main) extractOptionalDate(payload map[string]interface{}, key string) string {
+	_, date, err := m.dateParser.WithoutMetrics().Extract(payload, key)
+	if err != nil || date.Year() <= 1 {
+		return ""
+	}
+
+	return FormatDate(date)
+}
+
+func extractInt(payload map[string]interface{}, key string) (int, bool) {
+	switch v := payload[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case json.Number:
+		ret, err := strconv.Atoi(v.String())
+
+		return ret, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func nilIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
@@ -0,0 +1,218 @@
+package processing_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
+	"github.com/openshift-assisted/ccx-exporter/internal/domain/repo/mock"
+	"github.com/openshift-assisted/ccx-exporter/internal/processing"
+	"github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+)
+
+func clusterStateEvent(status, updatedAt string) entity.Event {
+	return entity.Event{
+		Name: "ClusterState",
+		Payload: map[string]interface{}{
+			"id":                   "cluster-1",
+			"created_at":           "2025-02-03T20:00:00.000Z",
+			"updated_at":           updatedAt,
+			"email_domain":         "example.com",
+			"status":               status,
+			"install_started_at":   "2025-02-03T20:30:00.000Z",
+			"install_completed_at": "0001-01-01T00:00:00.000Z",
+			"openshift_version":    "4.18.0",
+			"total_host_count":     float64(3),
+		},
+	}
+}
+
+func TestClusterSummary(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	main := processing.NewMain(hostRepo, projectionWriter).WithClusterSummaries(memoryStateStore{}, 0)
+
+	ctx := context.Background()
+
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil).AnyTimes()
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	projectionWriter.EXPECT().WriteProjectedClusterEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// Installing, then an error event
+	err := main.Process(ctx, clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	err = main.Process(ctx, entity.Event{
+		Name: "Event",
+		Payload: map[string]interface{}{
+			"cluster_id": "cluster-1",
+			"event_time": "2025-02-03T20:45:00.000Z",
+			"message":    "Host master-0 failed to reboot",
+			"severity":   "error",
+		},
+	})
+	require.NoError(t, err, "failed to process event")
+
+	// Terminal status: summary emitted once
+	projectionWriter.EXPECT().WriteClusterSummary(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, summary entity.ClusterSummary) error {
+			assert.Equal(t, map[string]interface{}{
+				"cluster_summary_id":   summary.ID,
+				"cluster_id":           "cluster-1",
+				"install_started_at":   "2025-02-03T20:30:00.000000Z",
+				"install_completed_at": nil,
+				"final_status":         "error",
+				"failed_stage":         "installing",
+				"failure_reason":       "Host master-0 failed to reboot",
+				"host_count":           3,
+				"openshift_version":    "4.18.0",
+				"updated_at":           "2025-02-03T20:50:00.000000Z",
+			}, summary.Payload)
+
+			return nil
+		})
+
+	err = main.Process(ctx, clusterStateEvent("error", "2025-02-03T20:50:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	err = main.Process(ctx, clusterStateEvent("error", "2025-02-03T20:55:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+}
+
+func TestClusterSummaryDateMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	registry := prometheus.NewRegistry()
+
+	dateParser, err := processing.DateParser{}.WithMetrics(registry, pipeline.MetricsConfig{Namespace: "test"})
+	require.NoError(t, err, "failed to register metrics")
+
+	main := processing.NewMain(hostRepo, projectionWriter).
+		WithDateParser(dateParser).
+		WithClusterSummaries(memoryStateStore{}, 0)
+
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil)
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil)
+
+	err = main.Process(context.Background(), clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	// Only updated_at is counted, not the dates read by the summary
+	expected := `
+# HELP test_date_formats_total Parsed dates by matching format.
+# TYPE test_date_formats_total counter
+test_date_formats_total{format="legacy"} 1
+`
+
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+	assert.NoError(t, err, "unexpected metrics")
+}
+
+func TestClusterSummaryOrdering(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	hostRepo := mock.NewMockHostState(ctrl)
+	projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+	store := memoryStateStore{}
+	main := processing.NewMain(hostRepo, projectionWriter).WithClusterSummaries(store, 0)
+
+	ctx := context.Background()
+
+	hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil).AnyTimes()
+	projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	err := main.Process(ctx, clusterStateEvent("finalizing", "2025-02-03T20:50:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	// Older cluster state processed after
+	err = main.Process(ctx, clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+	require.NoError(t, err, "failed to process cluster state")
+
+	summary := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(store["cluster_summary:cluster-1"], &summary))
+	assert.Equal(t, "finalizing", summary["status"])
+	assert.Equal(t, "finalizing", summary["last_stage"])
+}
+
+// conflictingStateStore simulates concurrent updates: the first conditional writes fail
+type conflictingStateStore struct {
+	memoryStateStore
+	conflicts *int
+}
+
+func (c conflictingStateStore) CompareAndSetState(ctx context.Context, kind, clusterID string, previous, value []byte, ttl time.Duration) (bool, error) {
+	if *c.conflicts > 0 {
+		*c.conflicts--
+
+		return false, nil
+	}
+
+	return c.memoryStateStore.CompareAndSetState(ctx, kind, clusterID, previous, value, ttl)
+}
+
+func TestClusterSummaryConflict(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name      string
+		conflicts int
+		stored    bool
+		retryable bool
+	}
+
+	testCases := []testCase{
+		{name: "retried", conflicts: 2, stored: true},
+		{name: "too many conflicts", conflicts: 3, retryable: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			hostRepo := mock.NewMockHostState(ctrl)
+			projectionWriter := mock.NewMockProjectionWriter(ctrl)
+
+			memory := memoryStateStore{}
+			conflicts := tc.conflicts
+			main := processing.NewMain(hostRepo, projectionWriter).WithClusterSummaries(conflictingStateStore{memoryStateStore: memory, conflicts: &conflicts}, 0)
+
+			hostRepo.EXPECT().GetHostStates(gomock.Any(), "cluster-1").Return(nil, nil)
+			projectionWriter.EXPECT().WriteProjectedClusterState(gomock.Any(), gomock.Any()).Return(nil)
+
+			err := main.Process(context.Background(), clusterStateEvent("installing", "2025-02-03T20:40:00.000Z"))
+
+			_, stored := memory["cluster_summary:cluster-1"]
+			assert.Equal(t, tc.stored, stored)
+
+			if !tc.retryable {
+				require.NoError(t, err, "failed to process cluster state")
+
+				return
+			}
+
+			pErr := pipeline.ErrProcessingError{}
+			require.ErrorAs(t, err, &pErr, "error should be a processing error")
+			assert.ErrorIs(t, err, pipeline.ErrRetryableError, "conflicts should be retried")
+		})
+	}
+}
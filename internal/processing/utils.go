@@ -1,14 +1,14 @@
 package processing
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/openshift-assisted/ccx-exporter/internal/anonymization"
 	"github.com/openshift-assisted/ccx-exporter/internal/domain/entity"
 )
 
@@ -20,33 +20,9 @@ var (
 	dateFormat = "<year>-<month>-<day>T<hour>:<minute>:<second>.<micro>Z"
 )
 
+// ExtractEventTime only accepts the legacy date format, see DateParser.ExtractEventTime
 func ExtractEventTime(event entity.Event) (time.Time, error) {
-	ret := time.Time{}
-
-	var dateStr string
-	var err error
-
-	switch event.Name {
-	case eventNameClusterState:
-		dateStr, err = ExtractString(event.Payload, "updated_at")
-	case eventNameEvent:
-		dateStr, err = ExtractString(event.Payload, "event_time")
-	case eventNameInfraEnvState:
-		dateStr, err = ExtractString(event.Payload, "updated_at")
-	default:
-		return ret, fmt.Errorf("unexpected event name: %s", event.Name)
-	}
-
-	if err != nil {
-		return ret, fmt.Errorf("failed to extract date time: %w", err)
-	}
-
-	ret, err = ValidateDate(dateStr)
-	if err != nil {
-		return ret, fmt.Errorf("invalid date %s: %w", dateStr, err)
-	}
-
-	return ret, nil
+	return DateParser{}.ExtractEventTime(event)
 }
 
 func ExtractString(payload map[string]interface{}, key string) (string, error) {
@@ -68,7 +44,7 @@ func ExtractString(payload map[string]interface{}, key string) (string, error) {
 }
 
 func ValidateDate(date string) (time.Time, error) {
-	ret, err := time.Parse("2006-01-02T15:04:05.9Z", date)
+	ret, err := time.Parse(legacyLayout, date)
 	if err != nil {
 		return ret, fmt.Errorf("failed to parse time: %w", err)
 	}
@@ -90,6 +66,16 @@ func FormatDate(date time.Time) string {
 	return replacer.Replace(dateFormat)
 }
 
+// unmarshalJSON decodes numbers as json.Number when useNumber is set, float64 otherwise
+func unmarshalJSON(data []byte, v interface{}, useNumber bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		decoder.UseNumber()
+	}
+
+	return decoder.Decode(v)
+}
+
 func CopyPayload(payload map[string]interface{}) map[string]interface{} {
 	ret := make(map[string]interface{})
 
@@ -100,41 +86,11 @@ func CopyPayload(payload map[string]interface{}) map[string]interface{} {
 	return ret
 }
 
-func HashValue(payload map[string]interface{}, key string) (string, error) {
-	value, err := ExtractString(payload, key)
-	if err != nil {
-		switch {
-		case errors.Is(err, errMissingKey):
-			return "", nil
-		case errors.Is(err, errEmptyValue):
-			return "", nil
-		default:
-			return "", fmt.Errorf("failed to extract string: %w", err)
-		}
-	}
-
-	return hash([]byte(value))
-}
-
 func HashPayload(payload map[string]interface{}) (string, error) {
 	payloadStr, err := json.Marshal(payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	return hash(payloadStr)
-}
-
-func hash(value []byte) (string, error) {
-	hash := md5.New()
-
-	_, err := hash.Write(value)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash value: %w", err)
-	}
-
-	hashBytes := hash.Sum(nil)
-	ret := hex.EncodeToString(hashBytes[:])
-
-	return ret, nil
+	return anonymization.MD5(payloadStr)
 }
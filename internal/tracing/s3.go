@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AddS3Middleware starts a span for each s3 operation (retries included), to use in s3.Options.APIOptions
+func AddS3Middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(s3Middleware{tracer: otel.Tracer(tracerName)}, middleware.After)
+}
+
+type s3Middleware struct {
+	tracer trace.Tracer
+}
+
+func (s3Middleware) ID() string {
+	return "ccx-exporter/tracing"
+}
+
+func (m s3Middleware) HandleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	operation := awsmiddleware.GetOperationName(ctx)
+
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "aws-api"),
+		attribute.String("rpc.service", "S3"),
+		attribute.String("rpc.method", operation),
+	}
+
+	if input, ok := in.Parameters.(*s3.PutObjectInput); ok {
+		attrs = append(attrs, attribute.String("aws.s3.bucket", deref(input.Bucket)), attribute.String("aws.s3.key", deref(input.Key)))
+	}
+
+	ctx, span := m.tracer.Start(ctx, "s3."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return out, metadata, err
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
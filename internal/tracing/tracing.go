@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/version"
+)
+
+const (
+	tracerName = "github.com/openshift-assisted/ccx-exporter/internal/tracing"
+	tracesPath = "/v1/traces"
+)
+
+// Init sets the global tracer provider & the W3C trace context propagator, spans are exported with OTLP/HTTP to
+// <endpoint>/v1/traces. Spans are no-op when tracing is disabled. Shutdown flushes the pending spans.
+func Init(ctx context.Context, conf config.Tracing) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if !conf.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(conf.Endpoint, "/")+tracesPath),
+		otlptracehttp.WithHeaders(conf.Headers),
+		otlptracehttp.WithTimeout(conf.Timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(conf.ServiceName),
+			semconv.ServiceVersion(version.Revision),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
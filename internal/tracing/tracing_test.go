@@ -0,0 +1,65 @@
+package tracing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+
+	"github.com/openshift-assisted/ccx-exporter/internal/config"
+	"github.com/openshift-assisted/ccx-exporter/internal/tracing"
+)
+
+type exportedRequest struct {
+	path    string
+	headers http.Header
+	size    int
+}
+
+func TestInit(t *testing.T) {
+	requests := make(chan exportedRequest, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		requests <- exportedRequest{path: r.URL.Path, headers: r.Header, size: len(body)}
+	}))
+	defer server.Close()
+
+	shutdown, err := tracing.Init(context.Background(), config.Tracing{
+		Enabled:     true,
+		Endpoint:    server.URL + "/",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		ServiceName: "ccx-exporter",
+		SampleRatio: 1,
+		Timeout:     time.Second,
+	})
+	require.NoError(t, err, "failed to init tracing")
+
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	// Pending spans are flushed
+	require.NoError(t, shutdown(context.Background()), "failed to shutdown tracing")
+
+	req := <-requests
+
+	assert.Equal(t, "/v1/traces", req.path)
+	assert.Equal(t, "application/x-protobuf", req.headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", req.headers.Get("Authorization"))
+	assert.Positive(t, req.size)
+}
+
+func TestInitDisabled(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), config.Tracing{})
+	require.NoError(t, err, "failed to init tracing")
+
+	assert.NoError(t, shutdown(context.Background()))
+}
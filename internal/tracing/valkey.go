@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ValkeyClient starts a span for each command (or pipeline) sent with Do, DoMulti, DoCache & DoMultiCache.
+// Other calls (e.g. Dedicated) are not traced.
+type ValkeyClient struct {
+	valkey.Client
+
+	tracer trace.Tracer
+}
+
+func NewValkeyClient(client valkey.Client) ValkeyClient {
+	return ValkeyClient{
+		Client: client,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+func (c ValkeyClient) Do(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+	// Commands are recycled once sent
+	ctx, span := c.start(ctx, commandName(cmd.Commands()), false)
+	defer span.End()
+
+	ret := c.Client.Do(ctx, cmd)
+
+	recordValkeyError(span, ret.Error())
+
+	return ret
+}
+
+func (c ValkeyClient) DoCache(ctx context.Context, cmd valkey.Cacheable, ttl time.Duration) valkey.ValkeyResult {
+	ctx, span := c.start(ctx, commandName(cmd.Commands()), true)
+	defer span.End()
+
+	ret := c.Client.DoCache(ctx, cmd, ttl)
+
+	recordValkeyError(span, ret.Error())
+
+	if ret.IsCacheHit() {
+		span.SetAttributes(attribute.Bool("db.valkey.cache_hit", true))
+	}
+
+	return ret
+}
+
+func (c ValkeyClient) DoMulti(ctx context.Context, multi ...valkey.Completed) []valkey.ValkeyResult {
+	names := make([]string, 0, len(multi))
+	for _, cmd := range multi {
+		names = append(names, commandName(cmd.Commands()))
+	}
+
+	ctx, span := c.startPipeline(ctx, names, false)
+	defer span.End()
+
+	ret := c.Client.DoMulti(ctx, multi...)
+
+	for _, resp := range ret {
+		recordValkeyError(span, resp.Error())
+	}
+
+	return ret
+}
+
+func (c ValkeyClient) DoMultiCache(ctx context.Context, multi ...valkey.CacheableTTL) []valkey.ValkeyResult {
+	names := make([]string, 0, len(multi))
+	for _, cmd := range multi {
+		names = append(names, commandName(cmd.Cmd.Commands()))
+	}
+
+	ctx, span := c.startPipeline(ctx, names, true)
+	defer span.End()
+
+	ret := c.Client.DoMultiCache(ctx, multi...)
+
+	for _, resp := range ret {
+		recordValkeyError(span, resp.Error())
+	}
+
+	return ret
+}
+
+func (c ValkeyClient) start(ctx context.Context, name string, cached bool) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "valkey "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "valkey"),
+			attribute.String("db.operation", name),
+			attribute.Bool("db.valkey.cache", cached),
+		),
+	)
+}
+
+func (c ValkeyClient) startPipeline(ctx context.Context, names []string, cached bool) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "valkey pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "valkey"),
+			attribute.StringSlice("db.valkey.commands", names),
+			attribute.Bool("db.valkey.cache", cached),
+		),
+	)
+}
+
+func commandName(commands []string) string {
+	if len(commands) == 0 {
+		return "unknown"
+	}
+
+	return commands[0]
+}
+
+// recordValkeyError marks the span as failed, nil replies are not errors
+func recordValkeyError(span trace.Span, err error) {
+	if err == nil || valkey.IsValkeyNil(err) {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+var errNotRunning = errors.New("runner not started")
+
+// Control reports the claims of the current session & controls the consumption at runtime.
+// A paused consumption stays paused across rebalancing.
+type Control struct {
+	lock         sync.Mutex
+	consumer     sarama.ConsumerGroup // Bound by the runner
+	paused       bool
+	memberID     string
+	generationID int32
+	claims       map[string]*claimProgress // By topic/partition
+}
+
+type claimProgress struct {
+	claim sarama.ConsumerGroupClaim
+	next  int64
+}
+
+// SessionStatus is the state of the current consumer group session
+type SessionStatus struct {
+	MemberID     string        `json:"memberId"`
+	GenerationID int32         `json:"generationId"`
+	Paused       bool          `json:"paused"`
+	Claims       []ClaimStatus `json:"claims"`
+}
+
+type ClaimStatus struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	InitialOffset int64  `json:"initialOffset"`
+	Offset        int64  `json:"offset"` // Next offset to consume
+	HighWaterMark int64  `json:"highWaterMark"`
+}
+
+func NewControl() *Control {
+	return &Control{
+		claims: make(map[string]*claimProgress),
+	}
+}
+
+// PauseAll stops fetching the claimed partitions until ResumeAll, including the ones claimed after a rebalancing.
+// Messages already fetched are still processed.
+func (c *Control) PauseAll() {
+	c.setPaused(true)
+}
+
+func (c *Control) ResumeAll() {
+	c.setPaused(false)
+}
+
+func (c *Control) setPaused(paused bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.paused = paused
+
+	if c.consumer == nil {
+		return
+	}
+
+	if paused {
+		c.consumer.PauseAll()
+	} else {
+		c.consumer.ResumeAll()
+	}
+}
+
+// Leave closes the consumer: its partitions are rebalanced to the other members & the runner returns.
+func (c *Control) Leave() error {
+	c.lock.Lock()
+	consumer := c.consumer
+	c.lock.Unlock()
+
+	if consumer == nil {
+		return errNotRunning
+	}
+
+	err := consumer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close consumer: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Control) bind(consumer sarama.ConsumerGroup) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.consumer = consumer
+}
+
+func (c *Control) Status() SessionStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ret := SessionStatus{
+		MemberID:     c.memberID,
+		GenerationID: c.generationID,
+		Paused:       c.paused,
+		Claims:       make([]ClaimStatus, 0, len(c.claims)),
+	}
+
+	for _, p := range c.claims {
+		ret.Claims = append(ret.Claims, ClaimStatus{
+			Topic:         p.claim.Topic(),
+			Partition:     p.claim.Partition(),
+			InitialOffset: p.claim.InitialOffset(),
+			Offset:        p.next,
+			HighWaterMark: p.claim.HighWaterMarkOffset(),
+		})
+	}
+
+	sort.Slice(ret.Claims, func(i, j int) bool {
+		if ret.Claims[i].Topic != ret.Claims[j].Topic {
+			return ret.Claims[i].Topic < ret.Claims[j].Topic
+		}
+
+		return ret.Claims[i].Partition < ret.Claims[j].Partition
+	})
+
+	return ret
+}
+
+func (c *Control) setup(session sarama.ConsumerGroupSession) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.memberID = session.MemberID()
+	c.generationID = session.GenerationID()
+	c.claims = make(map[string]*claimProgress)
+}
+
+// claim tracks a claim until the returned function is called.
+// Partition consumers are created after the session setup: a paused claim is paused here.
+func (c *Control) claim(claim sarama.ConsumerGroupClaim) (*claimProgress, func()) {
+	if c == nil {
+		return nil, func() {}
+	}
+
+	key := claimKey(claim.Topic(), claim.Partition())
+
+	ret := &claimProgress{
+		claim: claim,
+		next:  claim.InitialOffset(),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.claims[key] = ret
+
+	if c.paused && c.consumer != nil {
+		c.consumer.Pause(map[string][]int32{claim.Topic(): {claim.Partition()}})
+	}
+
+	return ret, func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.claims[key] == ret {
+			delete(c.claims, key)
+		}
+	}
+}
+
+func (c *Control) consumed(p *claimProgress, msg *sarama.ConsumerMessage) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p.next = msg.Offset + 1
+}
+
+func claimKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
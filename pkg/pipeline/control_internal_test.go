@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"github.com/IBM/sarama"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Helper
+
+type fakeConsumerGroup struct {
+	sarama.ConsumerGroup
+
+	pausedAll  bool
+	paused     map[string][]int32
+	closeCalls int
+}
+
+func (g *fakeConsumerGroup) PauseAll()  { g.pausedAll = true }
+func (g *fakeConsumerGroup) ResumeAll() { g.pausedAll = false }
+func (g *fakeConsumerGroup) Pause(partitions map[string][]int32) {
+	g.paused = partitions
+}
+
+func (g *fakeConsumerGroup) Close() error {
+	g.closeCalls++
+
+	return nil
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+}
+
+func (s fakeSession) MemberID() string    { return "member-1" }
+func (s fakeSession) GenerationID() int32 { return 3 }
+
+// Test
+
+var _ = Describe("Testing Control", func() {
+	var (
+		consumer *fakeConsumerGroup
+		control  *Control
+	)
+
+	BeforeEach(func() {
+		consumer = &fakeConsumerGroup{}
+		control = NewControl()
+	})
+
+	When("the runner is not started", func() {
+		It("should not leave", func() {
+			Expect(control.Leave()).To(MatchError(errNotRunning))
+		})
+	})
+
+	When("partitions are claimed", func() {
+		BeforeEach(func() {
+			control.bind(consumer)
+			control.setup(fakeSession{})
+		})
+
+		It("should report their progress", func() {
+			progress, release := control.claim(fakeClaim{topic: "events", partition: 1, initialOffset: 4, highWaterMark: 10})
+			_, releaseOther := control.claim(fakeClaim{topic: "events", partition: 0, initialOffset: 2, highWaterMark: 2})
+
+			control.consumed(progress, &sarama.ConsumerMessage{Offset: 6})
+
+			Expect(control.Status()).To(Equal(SessionStatus{
+				MemberID:     "member-1",
+				GenerationID: 3,
+				Claims: []ClaimStatus{
+					{Topic: "events", Partition: 0, InitialOffset: 2, Offset: 2, HighWaterMark: 2},
+					{Topic: "events", Partition: 1, InitialOffset: 4, Offset: 7, HighWaterMark: 10},
+				},
+			}))
+
+			release()
+			releaseOther()
+
+			Expect(control.Status().Claims).To(BeEmpty())
+		})
+
+		It("should keep them paused after a rebalancing", func() {
+			control.PauseAll()
+			Expect(consumer.pausedAll).To(BeTrue())
+
+			control.setup(fakeSession{})
+			_, release := control.claim(fakeClaim{topic: "events", partition: 1})
+			DeferCleanup(release)
+
+			Expect(consumer.paused).To(Equal(map[string][]int32{"events": {1}}))
+			Expect(control.Status().Paused).To(BeTrue())
+
+			control.ResumeAll()
+			Expect(consumer.pausedAll).To(BeFalse())
+			Expect(control.Status().Paused).To(BeFalse())
+		})
+
+		It("should close the consumer when leaving", func() {
+			Expect(control.Leave()).To(Succeed())
+			Expect(consumer.closeCalls).To(Equal(1))
+		})
+	})
+
+	When("disabled", func() {
+		It("should do nothing", func() {
+			var disabled *Control
+
+			disabled.bind(consumer)
+			disabled.setup(fakeSession{})
+
+			progress, release := disabled.claim(fakeClaim{topic: "events"})
+			disabled.consumed(progress, &sarama.ConsumerMessage{})
+			release()
+
+			Expect(progress).To(BeNil())
+		})
+	})
+})
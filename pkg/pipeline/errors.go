@@ -25,6 +25,7 @@ type Input struct {
 const (
 	UnknownCategory        = "unknown"
 	UnmarshalErrorCategory = "unmarshal"
+	ArchiveErrorCategory   = "archive"
 	PanicCategory          = "panic"
 )
 
@@ -1,19 +1,56 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 
 	"github.com/IBM/sarama"
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+var errTrailingData = errors.New("invalid character after top-level value")
+
+type messageKey struct{}
+
+// ContextWithMessage attaches the kafka message being processed to ctx
+func ContextWithMessage(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return context.WithValue(ctx, messageKey{}, msg)
+}
+
+// MessageFromContext returns the kafka message being processed, e.g. to read its timestamp
+func MessageFromContext(ctx context.Context) (*sarama.ConsumerMessage, bool) {
+	msg, ok := ctx.Value(messageKey{}).(*sarama.ConsumerMessage)
+
+	return msg, ok
+}
+
 type JSONHandler[Payload any] struct {
 	logger *logr.Logger
 
 	processing      Processing[Payload]
 	errorProcessing ErrorProcessing
+
+	// useNumber decodes numbers as json.Number instead of float64
+	useNumber bool
+
+	// archiver stores the records as received, nil when disabled
+	archiver Processing[*sarama.ConsumerMessage]
+
+	// tracker bounds the consumption to offset ranges, nil when unbounded
+	tracker *rangeTracker
+
+	// lag publishes the progress of every claim, nil when disabled
+	lag *lagMetrics
+
+	// health tracks the session & the messages in flight, nil when disabled
+	health *Health
+
+	// control reports the claims & pauses them, nil when disabled
+	control *Control
 }
 
 func NewJSONHandler[Payload any](processing Processing[Payload], errProcessing ErrorProcessing) JSONHandler[Payload] {
@@ -29,6 +66,49 @@ func (h JSONHandler[Payload]) WithLogger(logger logr.Logger) JSONHandler[Payload
 	return h
 }
 
+// WithUseNumber keeps numbers as json.Number: large integers are not rounded to float64.
+func (h JSONHandler[Payload]) WithUseNumber(useNumber bool) JSONHandler[Payload] {
+	h.useNumber = useNumber
+
+	return h
+}
+
+// WithArchiver processes every record before decoding it, even the invalid ones.
+// The record is processed anyway: an archiving failure is logged, and sent to the error processing only when the
+// processing succeeded, so that a record is never sent twice.
+func (h JSONHandler[Payload]) WithArchiver(archiver Processing[*sarama.ConsumerMessage]) JSONHandler[Payload] {
+	h.archiver = archiver
+
+	return h
+}
+
+// WithLagMetrics publishes the committed offset, the high watermark, the lag & the record time watermark of every claim.
+// offsets resolves the oldest offset of the partitions never committed, nil to publish their lag from the first message.
+func (h JSONHandler[Payload]) WithLagMetrics(registry prometheus.Registerer, config MetricsConfig, offsets OffsetGetter) (JSONHandler[Payload], error) {
+	lag, err := newLagMetrics(registry, config, offsets)
+	if err != nil {
+		return h, err
+	}
+
+	h.lag = lag
+
+	return h, nil
+}
+
+// WithHealth reports the group membership & the messages processed for too long, see Health.
+func (h JSONHandler[Payload]) WithHealth(health *Health) JSONHandler[Payload] {
+	h.health = health
+
+	return h
+}
+
+// WithControl reports the claims of the current session & pauses them on demand, see Control.
+func (h JSONHandler[Payload]) WithControl(control *Control) JSONHandler[Payload] {
+	h.control = control
+
+	return h
+}
+
 func (h JSONHandler[Payload]) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := session.Context()
 
@@ -38,38 +118,143 @@ func (h JSONHandler[Payload]) ConsumeClaim(session sarama.ConsumerGroupSession,
 		"initialOffset", claim.InitialOffset(),
 	)
 
-	for msg := range claim.Messages() {
-		// If a re-balancing occurred, context will be canceled
-		// Could also be a termination signal or anything
-		if ctx.Err() != nil {
-			break
-		}
+	if h.tracker.skip(claim) {
+		h.logInfo(0, "Nothing to consume", "topic", claim.Topic(), "partition", claim.Partition())
+
+		return nil
+	}
 
-		if msg == nil {
-			h.logInfo(1, "Nil message")
+	progress, release := h.control.claim(claim)
+	defer release()
 
-			continue
-		}
+	lag, stop := h.lag.track(ctx, claim)
+	defer stop()
 
-		h.logInfo(3, "Processing message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+	idle := h.tracker.idleTimer()
+	defer idle.stop()
 
-		payload := new(Payload)
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
 
-		err := json.Unmarshal(msg.Value, payload)
-		if err != nil { // Not retryable
-			h.processError(ctx, msg, NewErrProcessingError(err, UnmarshalErrorCategory, nil), session)
+			// If a re-balancing occurred, context will be canceled
+			// Could also be a termination signal or anything
+			if ctx.Err() != nil {
+				return nil
+			}
 
-			continue
-		}
+			if msg == nil {
+				h.logInfo(1, "Nil message")
+
+				continue
+			}
+
+			if h.tracker.isAfterEnd(msg) {
+				return nil
+			}
+
+			h.consumeMessage(ctx, msg, session)
+
+			lag.consumed(msg)
+			h.control.consumed(progress, msg)
+
+			if h.tracker.isLast(claim, msg) {
+				h.logInfo(0, "End of range reached", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
 
-		err = h.processing.Process(ctx, *payload)
-		if err != nil {
-			h.processError(ctx, msg, err, session)
+				return nil
+			}
 
-			continue
+			idle.reset()
+		case <-idle.C():
+			// The last offsets of the range are never delivered, e.g. a transaction marker
+			h.logInfo(0, "End of range reached, no message received", "topic", claim.Topic(), "partition", claim.Partition(), "highWaterMark", claim.HighWaterMarkOffset())
+
+			h.tracker.done(claim.Topic(), claim.Partition())
+
+			return nil
 		}
+	}
+}
+
+func (h JSONHandler[Payload]) consumeMessage(ctx context.Context, msg *sarama.ConsumerMessage, session sarama.ConsumerGroupSession) {
+	ctx, span := startConsumeSpan(ctx, msg)
+	defer span.End()
+
+	ctx = ContextWithMessage(ctx, msg)
+
+	h.logInfo(3, "Processing message", append(traceValues(ctx), "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)...)
+
+	end := h.health.begin(msg)
+	defer end()
+
+	archiveErr := h.archive(ctx, msg)
+
+	payload := new(Payload)
+
+	err := h.unmarshal(msg.Value, payload)
+	if err != nil { // Not retryable
+		recordError(span, err)
+
+		h.processError(ctx, msg, NewErrProcessingError(err, UnmarshalErrorCategory, nil), session)
+
+		return
+	}
+
+	err = h.processing.Process(ctx, *payload)
+	if err != nil {
+		recordError(span, err)
+
+		h.processError(ctx, msg, err, session)
+
+		return
+	}
+
+	// The record is sent once to the error processing: an archiving failure only when the processing succeeded
+	if archiveErr != nil {
+		recordError(span, archiveErr)
 
-		session.MarkMessage(msg, "")
+		h.processError(ctx, msg, archiveErr, session)
+
+		return
+	}
+
+	session.MarkMessage(msg, "")
+}
+
+// archive stores the record, a failure is logged & returned to be sent to the error processing.
+func (h JSONHandler[Payload]) archive(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	if h.archiver == nil {
+		return nil
+	}
+
+	err := h.archiver.Process(ctx, msg)
+	if err != nil {
+		h.logError(err, "Archiving failed", append(traceValues(ctx), "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)...)
+	}
+
+	return err
+}
+
+func (h JSONHandler[Payload]) unmarshal(data []byte, payload *Payload) error {
+	if !h.useNumber {
+		return json.Unmarshal(data, payload)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err := decoder.Decode(payload)
+	if err != nil {
+		return err
+	}
+
+	// Same behavior as json.Unmarshal: only one json value is expected
+	_, err = decoder.Token()
+	if !errors.Is(err, io.EOF) {
+		return errTrailingData
 	}
 
 	return nil
@@ -86,15 +271,15 @@ func (h JSONHandler[Payload]) processError(ctx context.Context, msg *sarama.Cons
 
 	defer session.MarkMessage(msg, "")
 
-	h.logError(pipelineError, "Processing failed")
+	h.logError(pipelineError, "Processing failed", traceValues(ctx)...)
 
 	processingError := createProcessingError(pipelineError, msg)
 
 	err = h.errorProcessing.Process(ctx, processingError)
 	if err != nil {
-		h.logError(err, "Error pipeline failed")
+		h.logError(err, "Error pipeline failed", traceValues(ctx)...)
 
-		h.dumpErrorContext(msg, processingError)
+		h.dumpErrorContext(ctx, msg, processingError)
 	}
 }
 
@@ -102,6 +287,11 @@ func (h JSONHandler[Payload]) processError(ctx context.Context, msg *sarama.Cons
 func (h JSONHandler[Payload]) Setup(session sarama.ConsumerGroupSession) error {
 	h.logInfo(0, "Setup to consume", "claims", session.Claims())
 
+	h.tracker.setup(session)
+
+	h.health.setMember(true)
+	h.control.setup(session)
+
 	return nil
 }
 
@@ -110,18 +300,22 @@ func (h JSONHandler[Payload]) Setup(session sarama.ConsumerGroupSession) error {
 func (h JSONHandler[Payload]) Cleanup(session sarama.ConsumerGroupSession) error {
 	h.logInfo(0, "Cleanup after consuming", "claims", session.Claims())
 
+	h.health.setMember(false)
+
 	return nil
 }
 
-func (h JSONHandler[Payload]) dumpErrorContext(msg *sarama.ConsumerMessage, err ErrProcessingError) {
+func (h JSONHandler[Payload]) dumpErrorContext(ctx context.Context, msg *sarama.ConsumerMessage, err ErrProcessingError) {
 	h.logger.Error(err,
 		"Failed to process message",
-		"kafka.topic", msg.Topic,
-		"kafka.partition", msg.Partition,
-		"kafka.offset", msg.Offset,
-		"kafka.payload", msg.Value,
-		"additionalInputs", err.AdditionalInputs,
-		"category", err.Category,
+		append(traceValues(ctx),
+			"kafka.topic", msg.Topic,
+			"kafka.partition", msg.Partition,
+			"kafka.offset", msg.Offset,
+			"kafka.payload", msg.Value,
+			"additionalInputs", err.AdditionalInputs,
+			"category", err.Category,
+		)...,
 	)
 }
 
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing JSONHandler unmarshal", func() {
+	DescribeTable("decoding a message",
+		func(useNumber bool, data string, expected map[string]interface{}, expectedErr error) {
+			h := JSONHandler[map[string]interface{}]{useNumber: useNumber}
+
+			payload := map[string]interface{}{}
+
+			err := h.unmarshal([]byte(data), &payload)
+			if expectedErr != nil {
+				Expect(err).To(MatchError(expectedErr))
+
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payload).To(Equal(expected))
+		},
+		Entry("large ints keep their precision with UseNumber", true, `{"size":9007199254740993}`,
+			map[string]interface{}{"size": json.Number("9007199254740993")}, nil),
+		Entry("large ints are rounded without UseNumber", false, `{"size":9007199254740993}`,
+			map[string]interface{}{"size": float64(9007199254740992)}, nil),
+		Entry("trailing whitespace is accepted", true, "{\"a\":1} \n\t",
+			map[string]interface{}{"a": json.Number("1")}, nil),
+		Entry("several values are rejected", true, `{"a":1} {"b":2}`, nil, errTrailingData),
+	)
+
+	It("should reject several values without UseNumber too", func() {
+		h := JSONHandler[map[string]interface{}]{}
+
+		payload := map[string]interface{}{}
+
+		Expect(h.unmarshal([]byte(`{"a":1} {"b":2}`), &payload)).To(HaveOccurred())
+	})
+})
+
+type processingFunc[Payload any] func(context.Context, Payload) error
+
+func (f processingFunc[Payload]) Process(ctx context.Context, payload Payload) error {
+	return f(ctx, payload)
+}
+
+type markingSession struct {
+	fakeSession
+
+	marked []int64
+}
+
+func (s *markingSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+var _ = Describe("Testing JSONHandler archive", func() {
+	var (
+		archiveErr    = NewErrProcessingError(errors.New("s3 down"), ArchiveErrorCategory, nil)
+		processingErr = errors.New("processing failed")
+	)
+
+	DescribeTable("consuming a message",
+		func(value string, archiveFails bool, processingFails bool, expectedCategories []string) {
+			archived := 0
+			processed := 0
+			categories := []string{}
+
+			archiver := processingFunc[*sarama.ConsumerMessage](func(context.Context, *sarama.ConsumerMessage) error {
+				archived++
+
+				if archiveFails {
+					return archiveErr
+				}
+
+				return nil
+			})
+
+			processing := processingFunc[map[string]interface{}](func(context.Context, map[string]interface{}) error {
+				processed++
+
+				if processingFails {
+					return processingErr
+				}
+
+				return nil
+			})
+
+			errProcessing := processingFunc[ErrProcessingError](func(_ context.Context, err ErrProcessingError) error {
+				categories = append(categories, err.Category)
+
+				return nil
+			})
+
+			h := NewJSONHandler[map[string]interface{}](processing, errProcessing).WithArchiver(archiver)
+			session := &markingSession{}
+
+			h.consumeMessage(context.Background(), &sarama.ConsumerMessage{Offset: 42, Value: []byte(value)}, session)
+
+			Expect(archived).To(Equal(1), "every record is archived")
+			Expect(session.marked).To(Equal([]int64{42}), "the record is marked once")
+			Expect(categories).To(Equal(expectedCategories))
+
+			if value == "{}" {
+				Expect(processed).To(Equal(1), "the record is processed even when archiving failed")
+			}
+		},
+		Entry("archived & processed", "{}", false, false, []string{}),
+		Entry("archiving failed", "{}", true, false, []string{ArchiveErrorCategory}),
+		Entry("archiving & processing failed", "{}", true, true, []string{UnknownCategory}),
+		Entry("processing failed", "{}", false, true, []string{UnknownCategory}),
+		Entry("invalid record, archiving failed", "not json", true, false, []string{UnmarshalErrorCategory}),
+	)
+})
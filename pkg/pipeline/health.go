@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/jonboulle/clockwork"
+)
+
+var (
+	errNotMember = errors.New("not a member of the consumer group")
+	errStuck     = errors.New("message processed for too long")
+)
+
+// Health tracks the consumption for the liveness & readiness probes.
+// Waiting for messages is healthy: only a message processed for longer than the timeout makes the runner unhealthy.
+type Health struct {
+	timeout time.Duration
+	clock   clockwork.Clock
+
+	lock     sync.Mutex
+	member   bool
+	inFlight map[string]time.Time // Processing start by topic/partition
+}
+
+func NewHealth(timeout time.Duration, clock clockwork.Clock) *Health {
+	return &Health{
+		timeout:  timeout,
+		clock:    clock,
+		inFlight: make(map[string]time.Time),
+	}
+}
+
+// Live fails when a message has been processed for longer than the timeout: the runner is stuck
+func (h *Health) Live(_ context.Context) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for partition, start := range h.inFlight {
+		if h.clock.Since(start) > h.timeout {
+			return fmt.Errorf("%w: %s since %s", errStuck, partition, start.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+// Member fails outside of a consumer group session: before joining & during a rebalancing
+func (h *Health) Member(_ context.Context) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.member {
+		return errNotMember
+	}
+
+	return nil
+}
+
+func (h *Health) setMember(member bool) {
+	if h == nil {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.member = member
+}
+
+// begin tracks a message until the returned function is called
+func (h *Health) begin(msg *sarama.ConsumerMessage) func() {
+	if h == nil {
+		return func() {}
+	}
+
+	key := claimKey(msg.Topic, msg.Partition)
+
+	h.lock.Lock()
+	h.inFlight[key] = h.clock.Now()
+	h.lock.Unlock()
+
+	return func() {
+		h.lock.Lock()
+		delete(h.inFlight, key)
+		h.lock.Unlock()
+	}
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/jonboulle/clockwork"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing Health", func() {
+	var (
+		clock  clockwork.FakeClock
+		health *Health
+	)
+
+	BeforeEach(func() {
+		clock = clockwork.NewFakeClock()
+		health = NewHealth(time.Minute, clock)
+	})
+
+	When("no session is active", func() {
+		It("should not be a member", func(ctx SpecContext) {
+			Expect(health.Member(ctx)).To(MatchError(errNotMember))
+
+			health.setMember(true)
+			Expect(health.Member(ctx)).To(Succeed())
+
+			health.setMember(false)
+			Expect(health.Member(ctx)).To(MatchError(errNotMember))
+		})
+	})
+
+	When("a message is processed for too long", func() {
+		It("should not be live until the processing ends", func(ctx SpecContext) {
+			Expect(health.Live(ctx)).To(Succeed())
+
+			end := health.begin(&sarama.ConsumerMessage{Topic: "events", Partition: 2})
+
+			clock.Advance(30 * time.Second)
+			Expect(health.Live(ctx)).To(Succeed())
+
+			clock.Advance(time.Minute)
+			Expect(health.Live(ctx)).To(MatchError(ContainSubstring("events/2")))
+
+			end()
+			Expect(health.Live(ctx)).To(Succeed())
+		})
+	})
+
+	When("disabled", func() {
+		It("should do nothing", func() {
+			var disabled *Health
+
+			disabled.setMember(true)
+			disabled.begin(&sarama.ConsumerMessage{})()
+		})
+	})
+})
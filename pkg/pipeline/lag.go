@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultLagRefreshInterval refreshes the lag of idle partitions, their high watermark moves without message consumed
+const defaultLagRefreshInterval = 10 * time.Second
+
+// OffsetGetter gets the oldest offset of a partition, e.g. sarama.Client
+type OffsetGetter interface {
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+}
+
+// lagMetrics publishes the progress of the consumer by topic & partition.
+// Series are only published by the instance owning the partition and deleted when it is released: summing them gives
+// the lag of the group while it is consuming. A group without member publishes nothing: these metrics can't replace an
+// external exporter (e.g. kafka-exporter) to scale the consumers from zero, e.g. with KEDA.
+type lagMetrics struct {
+	committedOffset *prometheus.GaugeVec
+	highWatermark   *prometheus.GaugeVec
+	lag             *prometheus.GaugeVec
+	eventTime       *prometheus.GaugeVec
+
+	// offsets resolves the oldest offset of a partition never committed, nil when unknown
+	offsets OffsetGetter
+
+	refreshInterval time.Duration
+}
+
+func newLagMetrics(registry prometheus.Registerer, config MetricsConfig, offsets OffsetGetter) (*lagMetrics, error) {
+	labels := []string{"topic", "partition"}
+
+	ret := &lagMetrics{
+		committedOffset: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Name:      "consumer_committed_offset",
+			Help:      "Next offset to consume, committed by the consumer group.",
+		}, labels),
+		highWatermark: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Name:      "consumer_high_watermark",
+			Help:      "Offset of the next message produced in the partition.",
+		}, labels),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Name:      "consumer_lag",
+			Help:      "Number of messages not consumed yet.",
+		}, labels),
+		eventTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Name:      "consumer_event_time_watermark_seconds",
+			Help:      "Most recent record timestamp consumed, in seconds since epoch.",
+		}, labels),
+		offsets:         offsets,
+		refreshInterval: defaultLagRefreshInterval,
+	}
+
+	for _, collector := range []prometheus.Collector{ret.committedOffset, ret.highWatermark, ret.lag, ret.eventTime} {
+		err := registry.Register(collector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+
+	return ret, nil
+}
+
+// track publishes the metrics of a claim until the returned function is called
+func (m *lagMetrics) track(ctx context.Context, claim sarama.ConsumerGroupClaim) (*partitionLag, func()) {
+	if m == nil {
+		return nil, func() {}
+	}
+
+	ret := &partitionLag{
+		metrics:   m,
+		claim:     claim,
+		topic:     claim.Topic(),
+		partition: strconv.Itoa(int(claim.Partition())),
+		next:      m.startOffset(claim),
+	}
+
+	ret.refresh()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ret.run(ctx)
+	}()
+
+	return ret, func() {
+		cancel()
+		wg.Wait()
+
+		ret.delete()
+	}
+}
+
+// startOffset resolves the initial offset of a partition never committed: OffsetNewest or OffsetOldest.
+// It stays negative when the oldest offset is unknown, the lag is then published from the first message.
+func (m *lagMetrics) startOffset(claim sarama.ConsumerGroupClaim) int64 {
+	ret := claim.InitialOffset()
+
+	switch {
+	case ret == sarama.OffsetNewest:
+		return claim.HighWaterMarkOffset()
+	case ret == sarama.OffsetOldest && m.offsets != nil:
+		oldest, err := m.offsets.GetOffset(claim.Topic(), claim.Partition(), sarama.OffsetOldest)
+		if err != nil {
+			return ret
+		}
+
+		return oldest
+	default:
+		return ret
+	}
+}
+
+// partitionLag holds the progress of a claim
+type partitionLag struct {
+	metrics   *lagMetrics
+	claim     sarama.ConsumerGroupClaim
+	topic     string
+	partition string
+
+	lock      sync.Mutex
+	next      int64 // Negative until the first message when the initial offset is unknown
+	eventTime time.Time
+}
+
+// consumed moves the progress after a message has been marked
+func (p *partitionLag) consumed(msg *sarama.ConsumerMessage) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+
+	p.next = msg.Offset + 1
+
+	if msg.Timestamp.After(p.eventTime) {
+		p.eventTime = msg.Timestamp
+	}
+
+	p.lock.Unlock()
+
+	p.refresh()
+}
+
+func (p *partitionLag) run(ctx context.Context) {
+	ticker := time.NewTicker(p.metrics.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refresh()
+		}
+	}
+}
+
+func (p *partitionLag) refresh() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	highWatermark := p.claim.HighWaterMarkOffset()
+
+	p.metrics.highWatermark.WithLabelValues(p.topic, p.partition).Set(float64(highWatermark))
+
+	if !p.eventTime.IsZero() {
+		p.metrics.eventTime.WithLabelValues(p.topic, p.partition).Set(float64(p.eventTime.UnixMilli()) / 1000)
+	}
+
+	if p.next < 0 {
+		return
+	}
+
+	p.metrics.committedOffset.WithLabelValues(p.topic, p.partition).Set(float64(p.next))
+	p.metrics.lag.WithLabelValues(p.topic, p.partition).Set(float64(max(highWatermark-p.next, 0)))
+}
+
+// delete removes the series once the partition is released, another instance may own it
+func (p *partitionLag) delete() {
+	for _, gauge := range []*prometheus.GaugeVec{p.metrics.committedOffset, p.metrics.highWatermark, p.metrics.lag, p.metrics.eventTime} {
+		gauge.DeleteLabelValues(p.topic, p.partition)
+	}
+}
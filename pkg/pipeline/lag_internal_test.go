@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeOffsets struct {
+	oldest int64
+	err    error
+}
+
+func (f fakeOffsets) GetOffset(_ string, _ int32, _ int64) (int64, error) {
+	return f.oldest, f.err
+}
+
+var _ = Describe("Testing lagMetrics", func() {
+	var (
+		registry *prometheus.Registry
+		metrics  *lagMetrics
+		claim    *fakeClaim
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		registry = prometheus.NewPedanticRegistry()
+
+		metrics, err = newLagMetrics(registry, MetricsConfig{Namespace: "test"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		claim = &fakeClaim{topic: "events", partition: 1, initialOffset: sarama.OffsetNewest, highWaterMark: 10}
+	})
+
+	When("the group has no committed offset", func() {
+		It("should start from the high watermark with OffsetNewest", func(ctx SpecContext) {
+			_, stop := metrics.track(ctx, claim)
+			DeferCleanup(stop)
+
+			Expect(testutil.ToFloat64(metrics.highWatermark.WithLabelValues("events", "1"))).To(BeEquivalentTo(10))
+			Expect(testutil.ToFloat64(metrics.committedOffset.WithLabelValues("events", "1"))).To(BeEquivalentTo(10))
+			Expect(testutil.ToFloat64(metrics.lag.WithLabelValues("events", "1"))).To(BeEquivalentTo(0))
+		})
+
+		It("should start from the oldest offset with OffsetOldest", func(ctx SpecContext) {
+			metrics.offsets = fakeOffsets{oldest: 4}
+			claim.initialOffset = sarama.OffsetOldest
+
+			_, stop := metrics.track(ctx, claim)
+			DeferCleanup(stop)
+
+			Expect(testutil.ToFloat64(metrics.committedOffset.WithLabelValues("events", "1"))).To(BeEquivalentTo(4))
+			Expect(testutil.ToFloat64(metrics.lag.WithLabelValues("events", "1"))).To(BeEquivalentTo(6))
+		})
+
+		It("should only publish the high watermark when the oldest offset is unknown", func(ctx SpecContext) {
+			metrics.offsets = fakeOffsets{err: sarama.ErrOutOfBrokers}
+			claim.initialOffset = sarama.OffsetOldest
+
+			_, stop := metrics.track(ctx, claim)
+			DeferCleanup(stop)
+
+			Expect(testutil.ToFloat64(metrics.highWatermark.WithLabelValues("events", "1"))).To(BeEquivalentTo(10))
+			Expect(testutil.CollectAndCount(metrics.lag)).To(Equal(0))
+			Expect(testutil.CollectAndCount(metrics.committedOffset)).To(Equal(0))
+		})
+	})
+
+	When("messages are consumed", func() {
+		It("should publish the lag & the record time watermark", func(ctx SpecContext) {
+			lag, stop := metrics.track(ctx, claim)
+			DeferCleanup(stop)
+
+			lag.consumed(&sarama.ConsumerMessage{Offset: 6, Timestamp: time.UnixMilli(2000)})
+			lag.consumed(&sarama.ConsumerMessage{Offset: 7, Timestamp: time.UnixMilli(1500)}) // Out of order
+
+			Expect(testutil.ToFloat64(metrics.committedOffset.WithLabelValues("events", "1"))).To(BeEquivalentTo(8))
+			Expect(testutil.ToFloat64(metrics.lag.WithLabelValues("events", "1"))).To(BeEquivalentTo(2))
+			Expect(testutil.ToFloat64(metrics.eventTime.WithLabelValues("events", "1"))).To(BeEquivalentTo(2))
+
+			By("refreshing the high watermark of an idle partition")
+			claim.highWaterMark = 15
+			lag.refresh()
+
+			Expect(testutil.ToFloat64(metrics.lag.WithLabelValues("events", "1"))).To(BeEquivalentTo(7))
+		})
+	})
+
+	When("the claim is released", func() {
+		It("should delete the series", func() {
+			lag, stop := metrics.track(context.Background(), claim)
+
+			lag.consumed(&sarama.ConsumerMessage{Offset: 9, Timestamp: time.UnixMilli(1000)})
+			Expect(testutil.CollectAndCount(registry)).To(Equal(4))
+
+			stop()
+
+			Expect(testutil.CollectAndCount(registry)).To(Equal(0))
+		})
+	})
+
+	When("disabled", func() {
+		It("should do nothing", func(ctx SpecContext) {
+			var disabled *lagMetrics
+
+			lag, stop := disabled.track(ctx, claim)
+			lag.consumed(&sarama.ConsumerMessage{Offset: 9})
+			stop()
+
+			Expect(lag).To(BeNil())
+		})
+	})
+})
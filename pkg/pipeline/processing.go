@@ -112,27 +112,39 @@ type durationDecorator[Payload any] struct {
 	processing Processing[Payload]
 	histogram  *prometheus.HistogramVec
 	clock      clockwork.Clock
+	name       func(Payload) string // nil when the histogram is not labeled by name
 }
 
 func NewDurationMetricsDecoratorProcessing[Payload any](p Processing[Payload], registry prometheus.Registerer, clock clockwork.Clock, config MetricsConfig) (Processing[Payload], error) {
+	return newDurationDecorator(p, registry, clock, config, nil)
+}
+
+// NewDurationByNameMetricsDecoratorProcessing also labels the histogram with the name of the payload, e.g. the event name.
+// Names must be bounded.
+func NewDurationByNameMetricsDecoratorProcessing[Payload any](p Processing[Payload], registry prometheus.Registerer, clock clockwork.Clock, config MetricsConfig, name func(Payload) string) (Processing[Payload], error) {
+	return newDurationDecorator(p, registry, clock, config, name)
+}
+
+func newDurationDecorator[Payload any](p Processing[Payload], registry prometheus.Registerer, clock clockwork.Clock, config MetricsConfig, name func(Payload) string) (Processing[Payload], error) {
 	ret := durationDecorator[Payload]{
 		processing: p,
 		clock:      clock,
-	}
-
-	buckets := config.Buckets
-	if len(buckets) == 0 {
-		buckets = []float64{10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+		name:       name,
 	}
 
 	opts := prometheus.HistogramOpts{
 		Namespace: config.Namespace,
 		Name:      "processing_duration_milliseconds",
 		Help:      "Time taken to process payload.",
-		Buckets:   buckets,
+		Buckets:   durationBuckets(config),
+	}
+
+	labels := []string{"failed"}
+	if name != nil {
+		labels = append(labels, "name")
 	}
 
-	histogram := prometheus.NewHistogramVec(opts, []string{"failed"})
+	histogram := prometheus.NewHistogramVec(opts, labels)
 
 	err := registry.Register(histogram)
 	if err != nil {
@@ -149,14 +161,77 @@ func (p durationDecorator[Payload]) Process(ctx context.Context, payload Payload
 
 	err := p.processing.Process(ctx, payload)
 
-	duration := p.clock.Since(start)
-	durationMilli := float64(duration/time.Millisecond) + float64(duration%time.Millisecond)/float64(time.Millisecond)
+	labels := []string{fmt.Sprintf("%v", err != nil)}
+	if p.name != nil {
+		labels = append(labels, p.name(payload))
+	}
 
-	p.histogram.WithLabelValues(fmt.Sprintf("%v", err != nil)).Observe(durationMilli)
+	p.histogram.WithLabelValues(labels...).Observe(milliseconds(p.clock.Since(start)))
 
 	return err
 }
 
+func durationBuckets(config MetricsConfig) []float64 {
+	if len(config.Buckets) == 0 {
+		return []float64{10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+	}
+
+	return config.Buckets
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration/time.Millisecond) + float64(duration%time.Millisecond)/float64(time.Millisecond)
+}
+
+// Stage Metrics
+
+// StageMetrics measures the duration of the stages of a processing, e.g. valkey_get, hash or s3_output_<bucket>.
+// A nil *StageMetrics measures nothing.
+type StageMetrics struct {
+	histogram *prometheus.HistogramVec
+	clock     clockwork.Clock
+}
+
+// NewStageMetrics measures stages from 0.1ms by default: most of them (hash, valkey commands) are sub-millisecond.
+func NewStageMetrics(registry prometheus.Registerer, clock clockwork.Clock, config MetricsConfig) (*StageMetrics, error) {
+	buckets := config.Buckets
+	if len(buckets) == 0 {
+		buckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+	}
+
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Name:      "stage_duration_milliseconds",
+		Help:      "Time taken by a processing stage, by stage.",
+		Buckets:   buckets,
+	}, []string{"stage", "failed"})
+
+	err := registry.Register(histogram)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metric: %w", err)
+	}
+
+	ret := &StageMetrics{
+		histogram: histogram,
+		clock:     clock,
+	}
+
+	return ret, nil
+}
+
+// Start measures stage until the returned func is called with the stage result
+func (s *StageMetrics) Start(stage string) func(err error) {
+	if s == nil {
+		return func(error) {}
+	}
+
+	start := s.clock.Now()
+
+	return func(err error) {
+		s.histogram.WithLabelValues(stage, fmt.Sprintf("%v", err != nil)).Observe(milliseconds(s.clock.Since(start)))
+	}
+}
+
 // Error Metric Processing
 
 type errorCountProcessing struct {
@@ -499,6 +499,123 @@ var _ = Describe("Testing duration metrics decorator", func() {
 	})
 })
 
+var _ = Describe("Testing duration by name metrics decorator", func() {
+	var registry *prometheus.Registry
+	var metrics pipeline.Processing[Data]
+	var proc *SlowProcessor
+
+	BeforeEach(func() {
+		registry = prometheus.NewPedanticRegistry()
+
+		fakeClock := clockwork.NewFakeClock()
+		proc = NewSlowProcessor(fakeClock)
+
+		var err error
+
+		metrics, err = pipeline.NewDurationByNameMetricsDecoratorProcessing(proc, registry, fakeClock,
+			pipeline.MetricsConfig{Namespace: "test", Buckets: []float64{20, 200}},
+			func(Data) string { return "data" },
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("a message is processed", func() {
+		BeforeEach(func() {
+			proc.Sleep = 50 * time.Millisecond
+
+			Expect(metrics.Process(context.TODO(), data)).To(Succeed())
+		})
+
+		It("should label the metric with the name", func() {
+			metrics, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Metric).To(HaveLen(1))
+
+			metric := metrics[0].Metric[0]
+			Expect(metric.Label).To(ConsistOf(
+				&promdto.LabelPair{Name: pointer("failed"), Value: pointer("false")},
+				&promdto.LabelPair{Name: pointer("name"), Value: pointer("data")},
+			))
+			Expect(*metric.Histogram.SampleSum).To(BeEquivalentTo(50))
+		})
+	})
+})
+
+var _ = Describe("Testing stage metrics", func() {
+	var registry *prometheus.Registry
+	var fakeClock clockwork.FakeClock
+	var stages *pipeline.StageMetrics
+
+	BeforeEach(func() {
+		registry = prometheus.NewPedanticRegistry()
+		fakeClock = clockwork.NewFakeClock()
+
+		var err error
+
+		stages, err = pipeline.NewStageMetrics(registry, fakeClock, pipeline.MetricsConfig{Namespace: "test", Buckets: []float64{20, 200}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("stages are measured", func() {
+		BeforeEach(func() {
+			end := stages.Start("hash")
+			fakeClock.Advance(5 * time.Millisecond)
+			end(nil)
+
+			end = stages.Start("valkey_get")
+			fakeClock.Advance(100 * time.Millisecond)
+			end(errOneError)
+		})
+
+		It("should return a metric by stage", func() {
+			metrics, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Metric).To(HaveLen(2))
+
+			hash := filterMetricByLabel(metrics[0].Metric, "stage", "hash")
+			Expect(hash).NotTo(BeNil())
+			Expect(*hash.Histogram.SampleSum).To(BeEquivalentTo(5))
+
+			get := filterMetricByLabel(metrics[0].Metric, "stage", "valkey_get")
+			Expect(get).NotTo(BeNil())
+			Expect(get.Label).To(ContainElement(&promdto.LabelPair{Name: pointer("failed"), Value: pointer("true")}))
+			Expect(*get.Histogram.SampleSum).To(BeEquivalentTo(100))
+		})
+	})
+
+	When("buckets are not configured", func() {
+		It("should measure sub-millisecond stages", func() {
+			registry := prometheus.NewPedanticRegistry()
+
+			stages, err := pipeline.NewStageMetrics(registry, fakeClock, pipeline.MetricsConfig{Namespace: "test"})
+			Expect(err).NotTo(HaveOccurred())
+
+			end := stages.Start("hash")
+			fakeClock.Advance(200 * time.Microsecond)
+			end(nil)
+
+			metrics, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+
+			buckets := metrics[0].Metric[0].Histogram.Bucket
+			Expect(*buckets[0].UpperBound).To(BeEquivalentTo(0.1))
+			Expect(*buckets[0].CumulativeCount).To(BeEquivalentTo(0))
+			Expect(*buckets[1].UpperBound).To(BeEquivalentTo(0.25))
+			Expect(*buckets[1].CumulativeCount).To(BeEquivalentTo(1))
+		})
+	})
+
+	When("disabled", func() {
+		It("should measure nothing", func() {
+			var disabled *pipeline.StageMetrics
+
+			disabled.Start("hash")(nil)
+		})
+	})
+})
+
 // Test Error Duration
 
 var _ = Describe("Testing error metrics decorator", func() {
@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// rangeIdleTimeout ends a partition receiving no message before the end of its range: its last offsets are never
+// delivered, e.g. transaction markers, aborted or compacted records.
+const rangeIdleTimeout = 30 * time.Second
+
+// OffsetRange is the [Start, End) range of offsets to consume in a partition
+type OffsetRange struct {
+	Start int64
+	End   int64
+}
+
+// OffsetRanges are the ranges to consume by topic & partition
+type OffsetRanges map[string]map[int32]OffsetRange
+
+func (r OffsetRanges) lookup(topic string, partition int32) (OffsetRange, bool) {
+	ret, found := r[topic][partition]
+
+	return ret, found
+}
+
+// rangeTracker cancels the consumption once every partition has reached the end of its range
+type rangeTracker struct {
+	ranges      OffsetRanges
+	cancel      context.CancelFunc
+	idleTimeout time.Duration
+
+	lock      sync.Mutex
+	remaining map[string]map[int32]struct{}
+}
+
+func newRangeTracker(ranges OffsetRanges, cancel context.CancelFunc) *rangeTracker {
+	ret := &rangeTracker{
+		ranges:      ranges,
+		cancel:      cancel,
+		idleTimeout: rangeIdleTimeout,
+		remaining:   make(map[string]map[int32]struct{}),
+	}
+
+	for topic, partitions := range ranges {
+		for partition, r := range partitions {
+			if r.Start >= r.End {
+				continue
+			}
+
+			if ret.remaining[topic] == nil {
+				ret.remaining[topic] = make(map[int32]struct{})
+			}
+
+			ret.remaining[topic][partition] = struct{}{}
+		}
+	}
+
+	if len(ret.remaining) == 0 {
+		cancel()
+	}
+
+	return ret
+}
+
+// setup starts the partitions of a new consumer group at the beginning of their range.
+// Marking an offset never goes backward: a rebalanced partition resumes where it was.
+func (t *rangeTracker) setup(session sarama.ConsumerGroupSession) {
+	if t == nil {
+		return
+	}
+
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			r, found := t.ranges.lookup(topic, partition)
+			if found {
+				session.MarkOffset(topic, partition, r.Start, "")
+			}
+		}
+	}
+}
+
+// skip reports whether the claim has nothing to consume: partition without range or range already consumed
+func (t *rangeTracker) skip(claim sarama.ConsumerGroupClaim) bool {
+	if t == nil {
+		return false
+	}
+
+	r, found := t.ranges.lookup(claim.Topic(), claim.Partition())
+	if !found {
+		return true
+	}
+
+	if claim.InitialOffset() >= r.End {
+		t.done(claim.Topic(), claim.Partition())
+
+		return true
+	}
+
+	return false
+}
+
+// isAfterEnd reports whether msg is out of the range of its partition
+func (t *rangeTracker) isAfterEnd(msg *sarama.ConsumerMessage) bool {
+	if t == nil {
+		return false
+	}
+
+	r, _ := t.ranges.lookup(msg.Topic, msg.Partition)
+	if msg.Offset < r.End {
+		return false
+	}
+
+	t.done(msg.Topic, msg.Partition)
+
+	return true
+}
+
+// isLast reports whether msg is the last message of the range of its partition.
+// The range is bounded by the high watermark of the claim: the partition may have been truncated since the range was computed.
+func (t *rangeTracker) isLast(claim sarama.ConsumerGroupClaim, msg *sarama.ConsumerMessage) bool {
+	if t == nil {
+		return false
+	}
+
+	r, _ := t.ranges.lookup(msg.Topic, msg.Partition)
+
+	end := r.End
+	if hwm := claim.HighWaterMarkOffset(); hwm > 0 && hwm < end {
+		end = hwm
+	}
+
+	if msg.Offset+1 < end {
+		return false
+	}
+
+	t.done(msg.Topic, msg.Partition)
+
+	return true
+}
+
+// idleTimer fires when a claim receives no message for the idle timeout, it never fires when unbounded
+func (t *rangeTracker) idleTimer() *idleTimer {
+	if t == nil {
+		return nil
+	}
+
+	return &idleTimer{
+		timer:   time.NewTimer(t.idleTimeout),
+		timeout: t.idleTimeout,
+	}
+}
+
+func (t *rangeTracker) done(topic string, partition int32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.remaining[topic], partition)
+
+	if len(t.remaining[topic]) == 0 {
+		delete(t.remaining, topic)
+	}
+
+	if len(t.remaining) == 0 {
+		t.cancel()
+	}
+}
+
+type idleTimer struct {
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+// C is nil, i.e. blocks forever, when unbounded
+func (i *idleTimer) C() <-chan time.Time {
+	if i == nil {
+		return nil
+	}
+
+	return i.timer.C
+}
+
+func (i *idleTimer) reset() {
+	if i == nil {
+		return
+	}
+
+	if !i.timer.Stop() {
+		select {
+		case <-i.timer.C:
+		default:
+		}
+	}
+
+	i.timer.Reset(i.timeout)
+}
+
+func (i *idleTimer) stop() {
+	if i == nil {
+		return
+	}
+
+	i.timer.Stop()
+}
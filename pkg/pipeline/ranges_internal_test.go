@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Helper
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+
+	topic         string
+	partition     int32
+	initialOffset int64
+	highWaterMark int64
+}
+
+func (c fakeClaim) Topic() string              { return c.topic }
+func (c fakeClaim) Partition() int32           { return c.partition }
+func (c fakeClaim) InitialOffset() int64       { return c.initialOffset }
+func (c fakeClaim) HighWaterMarkOffset() int64 { return c.highWaterMark }
+
+// Test
+
+var _ = Describe("Testing rangeTracker", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		tracker *rangeTracker
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		tracker = newRangeTracker(OffsetRanges{
+			"events": {
+				0: {Start: 10, End: 12},
+				1: {Start: 5, End: 7},
+				2: {Start: 3, End: 3}, // Empty
+			},
+		}, cancel)
+	})
+
+	When("a claim has nothing to consume", func() {
+		It("should be skipped", func() {
+			Expect(tracker.skip(fakeClaim{topic: "events", partition: 2, initialOffset: 3})).To(BeTrue())
+			Expect(tracker.skip(fakeClaim{topic: "events", partition: 3})).To(BeTrue())
+			Expect(tracker.skip(fakeClaim{topic: "events", partition: 0, initialOffset: 10})).To(BeFalse())
+		})
+	})
+
+	When("every partition reaches the end of its range", func() {
+		It("should cancel the consumption", func() {
+			claim := fakeClaim{topic: "events", partition: 0, highWaterMark: 20}
+
+			Expect(tracker.isLast(claim, &sarama.ConsumerMessage{Topic: "events", Partition: 0, Offset: 10})).To(BeFalse())
+			Expect(tracker.isLast(claim, &sarama.ConsumerMessage{Topic: "events", Partition: 0, Offset: 11})).To(BeTrue())
+			Expect(ctx.Err()).ToNot(HaveOccurred())
+
+			// Compacted partition: the end is reached without its last message
+			Expect(tracker.isAfterEnd(&sarama.ConsumerMessage{Topic: "events", Partition: 1, Offset: 8})).To(BeTrue())
+			Expect(ctx.Err()).To(HaveOccurred())
+		})
+	})
+
+	When("the high watermark is before the end of the range", func() {
+		It("should end the partition at the high watermark", func() {
+			claim := fakeClaim{topic: "events", partition: 1, highWaterMark: 6}
+
+			Expect(tracker.isLast(claim, &sarama.ConsumerMessage{Topic: "events", Partition: 1, Offset: 5})).To(BeTrue())
+		})
+	})
+
+	When("a partition receives no message", func() {
+		It("should fire the idle timer", func() {
+			tracker.idleTimeout = 10 * time.Millisecond
+
+			idle := tracker.idleTimer()
+			defer idle.stop()
+
+			idle.reset()
+			Eventually(idle.C()).Should(Receive())
+		})
+	})
+
+	When("every range is empty", func() {
+		It("should cancel the consumption immediately", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			newRangeTracker(OffsetRanges{"events": {0: {Start: 3, End: 3}}}, cancel)
+			Expect(ctx.Err()).To(HaveOccurred())
+		})
+	})
+
+	When("the consumption is unbounded", func() {
+		It("should consume everything", func() {
+			var unbounded *rangeTracker
+
+			Expect(unbounded.skip(fakeClaim{})).To(BeFalse())
+			Expect(unbounded.isAfterEnd(&sarama.ConsumerMessage{})).To(BeFalse())
+			Expect(unbounded.isLast(fakeClaim{}, &sarama.ConsumerMessage{})).To(BeFalse())
+			Expect(unbounded.idleTimer().C()).To(BeNil())
+		})
+	})
+})
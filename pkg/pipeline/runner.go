@@ -7,6 +7,7 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Runner[Payload any] struct {
@@ -15,6 +16,9 @@ type Runner[Payload any] struct {
 
 	handler JSONHandler[Payload]
 
+	// ranges bounds the consumption, nil to consume forever
+	ranges OffsetRanges
+
 	logger *logr.Logger
 }
 
@@ -35,7 +39,69 @@ func (r Runner[Payload]) WithLogger(logger logr.Logger) Runner[Payload] {
 	return r
 }
 
+// WithUseNumber keeps numbers as json.Number: large integers are not rounded to float64.
+func (r Runner[Payload]) WithUseNumber(useNumber bool) Runner[Payload] {
+	r.handler = r.handler.WithUseNumber(useNumber)
+
+	return r
+}
+
+// WithArchiver stores every consumed record before decoding it, see JSONHandler.WithArchiver
+func (r Runner[Payload]) WithArchiver(archiver Processing[*sarama.ConsumerMessage]) Runner[Payload] {
+	r.handler = r.handler.WithArchiver(archiver)
+
+	return r
+}
+
+// WithLagMetrics publishes the progress of the consumer by topic & partition, see JSONHandler.WithLagMetrics
+func (r Runner[Payload]) WithLagMetrics(registry prometheus.Registerer, config MetricsConfig, offsets OffsetGetter) (Runner[Payload], error) {
+	handler, err := r.handler.WithLagMetrics(registry, config, offsets)
+	if err != nil {
+		return r, err
+	}
+
+	r.handler = handler
+
+	return r, nil
+}
+
+// WithHealth tracks the consumption for the probes, see JSONHandler.WithHealth
+func (r Runner[Payload]) WithHealth(health *Health) Runner[Payload] {
+	r.handler = r.handler.WithHealth(health)
+
+	return r
+}
+
+// WithControl allows to pause, resume & stop the consumption at runtime, see JSONHandler.WithControl
+func (r Runner[Payload]) WithControl(control *Control) Runner[Payload] {
+	r.handler = r.handler.WithControl(control)
+
+	return r
+}
+
+// WithOffsetRanges only consumes the given ranges: partitions start at the beginning of their range,
+// Run returns once every partition has reached the end of its range.
+// It's meant to be used with a new consumer group, partitions without range are not consumed.
+func (r Runner[Payload]) WithOffsetRanges(ranges OffsetRanges) Runner[Payload] {
+	r.ranges = ranges
+
+	return r
+}
+
 func (r Runner[Payload]) Run(ctx context.Context) error {
+	handler := r.handler
+
+	if r.ranges != nil {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		handler.tracker = newRangeTracker(r.ranges, cancel)
+	}
+
+	handler.control.bind(r.consumer)
+
 	go func() {
 		for err := range r.consumer.Errors() {
 			r.logError(err, "kafka consumer error")
@@ -43,7 +109,7 @@ func (r Runner[Payload]) Run(ctx context.Context) error {
 	}()
 
 	for {
-		err := r.consumer.Consume(ctx, r.topics, r.handler)
+		err := r.consumer.Consume(ctx, r.topics, handler)
 		if err != nil {
 			switch {
 			case errors.Is(err, sarama.ErrClosedConsumerGroup):
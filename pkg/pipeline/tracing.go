@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/openshift-assisted/ccx-exporter/pkg/pipeline"
+
+// headersCarrier reads & writes the propagated context, e.g. traceparent, in the kafka headers
+type headersCarrier []*sarama.RecordHeader
+
+func (c headersCarrier) Get(key string) string {
+	for _, h := range c {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// Set is not used by the consumer: headers are only extracted
+func (c headersCarrier) Set(string, string) {}
+
+func (c headersCarrier) Keys() []string {
+	ret := make([]string, 0, len(c))
+
+	for _, h := range c {
+		if h != nil {
+			ret = append(ret, string(h.Key))
+		}
+	}
+
+	return ret
+}
+
+// startConsumeSpan starts the span of a message, child of the producer span when its context is propagated in the headers
+func startConsumeSpan(ctx context.Context, msg *sarama.ConsumerMessage) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headersCarrier(msg.Headers))
+
+	return otel.Tracer(tracerName).Start(ctx, "consume "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int64("messaging.kafka.destination.partition", int64(msg.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+		),
+	)
+}
+
+// traceValues are the log key & values of the span in ctx, nil without span
+func traceValues(ctx context.Context) []any {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	return []any{"trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String()}
+}
+
+// recordError marks the span as failed
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Tracing Processing
+
+type tracingDecorator[Payload any] struct {
+	processing Processing[Payload]
+	tracer     trace.Tracer
+	name       string
+}
+
+// NewTracingDecoratorProcessing wraps the processing in a span, spans are no-op until a tracer provider is set
+func NewTracingDecoratorProcessing[Payload any](p Processing[Payload], name string) Processing[Payload] {
+	return tracingDecorator[Payload]{
+		processing: p,
+		tracer:     otel.Tracer(tracerName),
+		name:       name,
+	}
+}
+
+func (p tracingDecorator[Payload]) Process(ctx context.Context, payload Payload) error {
+	ctx, span := p.tracer.Start(ctx, p.name)
+	defer span.End()
+
+	err := p.processing.Process(ctx, payload)
+
+	recordError(span, err)
+
+	return err
+}
@@ -65,7 +65,7 @@ var _ = Describe("Checking cluster state event idempotency", func() {
 
 			Eventually(func(g Gomega, ctx context.Context) {
 				for i, bucket := range testConfig.OutputS3Buckets {
-					objects, err := testContext.ListS3Objects(ctx, bucket, "")
+					objects, err := testContext.ListS3Objects(ctx, bucket, e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i))
 					g.Expect(err).NotTo(HaveOccurred())
 					g.Expect(len(objects)).To(Equal(1))
 					g.Expect(objects[0]).To(ContainSubstring(e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i)))
@@ -95,7 +95,7 @@ var _ = Describe("Checking cluster state event idempotency", func() {
 				By("having only 1 key in s3 with a stable content")
 				Eventually(func(g Gomega, ctx context.Context) {
 					for i, bucket := range testConfig.OutputS3Buckets {
-						objects, err := testContext.ListS3Objects(ctx, bucket, "")
+						objects, err := testContext.ListS3Objects(ctx, bucket, e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i))
 						g.Expect(err).NotTo(HaveOccurred())
 						g.Expect(len(objects)).To(Equal(1))
 						g.Expect(objects[0]).To(ContainSubstring(e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i)))
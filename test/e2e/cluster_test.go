@@ -64,7 +64,7 @@ var _ = Describe("Checking cluster state happy path", func() {
 			By("eventually creating a file in s3 (result) with expected output")
 			Eventually(func(g Gomega, ctx context.Context) {
 				for i, bucket := range testConfig.OutputS3Buckets {
-					objects, err := testContext.ListS3Objects(ctx, bucket, "")
+					objects, err := testContext.ListS3Objects(ctx, bucket, e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i))
 					g.Expect(err).NotTo(HaveOccurred())
 					g.Expect(len(objects)).To(Equal(1))
 					g.Expect(objects[0]).To(ContainSubstring(e2e.S3Path(e2e.EventTypeClusters, e2e.EventDate, i)))
@@ -82,6 +82,15 @@ var _ = Describe("Checking cluster state happy path", func() {
 				}
 			}).WithContext(ctx).WithTimeout(time.Minute).WithPolling(5 * time.Second).Should(Succeed())
 
+			By("eventually creating one file per host state in s3 (result)")
+			Eventually(func(g Gomega, ctx context.Context) {
+				for i, bucket := range testConfig.OutputS3Buckets {
+					objects, err := testContext.ListS3Objects(ctx, bucket, e2e.S3Path(e2e.EventTypeHosts, e2e.EventDate, i))
+					g.Expect(err).NotTo(HaveOccurred())
+					g.Expect(len(objects)).To(Equal(4))
+				}
+			}).WithContext(ctx).WithTimeout(time.Minute).WithPolling(5 * time.Second).Should(Succeed())
+
 			By("eventually incrementing the data count metrics")
 			Eventually(func(g Gomega, ctx context.Context) {
 				metric, err := testContext.GetMetric(ctx, e2e.DataCountMetricFamily, e2e.KeyValue{Key: "name", Value: "ClusterState"})
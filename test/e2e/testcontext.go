@@ -43,6 +43,7 @@ const (
 	EventTypeEvents    EventType = ".events"
 	EventTypeClusters  EventType = ".clusters"
 	EventTypeInfraEnvs EventType = ".infra_envs"
+	EventTypeHosts     EventType = ".hosts"
 )
 
 type TestConfig struct {